@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"nep/configs"
+	"nep/utils"
+)
+
+// directPackage is a package installed from a source specifier rather than the registry.
+type directPackage struct {
+	specifier    string
+	response     *utils.Response
+	dependencies map[string]string
+	// dir is the directory of a file: package, which is copied as is
+	dir string
+}
+
+// directSource answers for packages installed from source specifiers and
+// defers every other package to the wrapped source.
+type directSource struct {
+	packageSource
+	inst *installer
+}
+
+func newDirectSource(inst *installer, source packageSource) *directSource {
+	return &directSource{packageSource: source, inst: inst}
+}
+
+func (s *directSource) Versions(name string) ([]string, error) {
+	if pkg, ok := s.inst.direct[name]; ok {
+		return []string{pkg.response.Data.Version}, nil
+	}
+	return s.packageSource.Versions(name)
+}
+
+// Dependencies also pins the dependencies that are themselves declared with a
+// source specifier, so the resolver never looks them up in the registry.
+func (s *directSource) Dependencies(name, version string) (map[string]string, error) {
+	dependencies, err := s.packageSource.Dependencies(name, version)
+	// Relative file: paths are resolved against the directory of the declaring package
+	var baseDir string
+	if pkg, ok := s.inst.direct[name]; ok {
+		dependencies, err = pkg.dependencies, nil
+		baseDir = pkg.dir
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dep := range utils.SortedKeys(dependencies) {
+		if utils.IsSourceSpecifier(dependencies[dep]) {
+			if err := s.inst.pin(dep, dependencies[dep], baseDir); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return dependencies, nil
+}
+
+func (s *directSource) fetch(name, version string) (*utils.Response, error) {
+	if pkg, ok := s.inst.direct[name]; ok {
+		return pkg.response, nil
+	}
+	return s.packageSource.fetch(name, version)
+}
+
+// pinRoots pins every root dependency declared with a source specifier.
+func (inst *installer) pinRoots() error {
+	for _, name := range utils.SortedKeys(inst.roots) {
+		if utils.IsSourceSpecifier(inst.roots[name]) {
+			if err := inst.pin(name, inst.roots[name], inst.projectPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pin resolves a source specifier to a single version of a package, with
+// relative file: paths taken from baseDir.
+// The first specifier seen for a package wins, so the project's own takes
+// precedence over those of its dependencies.
+func (inst *installer) pin(name, specifier, baseDir string) error {
+	if _, ok := inst.direct[name]; ok {
+		return nil
+	}
+
+	pkg, err := inst.resolveDirect(name, specifier, baseDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s from %s: %v", name, specifier, err)
+	}
+	inst.direct[name] = pkg
+	inst.pinned[name] = pkg.response.Data.Version
+	return nil
+}
+
+// resolveDirect reads the metadata of a package straight from its repository or directory.
+// baseDir is the directory of the declaring package, or empty when it has none on disk.
+func (inst *installer) resolveDirect(name, specifier, baseDir string) (*directPackage, error) {
+	source, _ := utils.ParseSourceSpecifier(specifier)
+
+	if source.IsLocal() {
+		dir := source.Path
+		if !filepath.IsAbs(dir) {
+			if baseDir == "" {
+				return nil, fmt.Errorf("relative paths are only allowed in the project and in local packages")
+			}
+			dir = filepath.Join(baseDir, dir)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", dir)
+		}
+
+		dependencies, err := utils.ReadPackageDependencies(dir)
+		if err != nil {
+			return nil, err
+		}
+		return &directPackage{
+			specifier:    specifier,
+			response:     &utils.Response{Key: name, Data: utils.Data{GithubURL: dir, Version: utils.ReadPackageVersion(dir, "0.0.0")}},
+			dependencies: dependencies,
+			dir:          dir,
+		}, nil
+	}
+
+	// The locked commit is kept until the package is explicitly updated
+	if locked, ok := inst.lock.Packages[name]; ok && locked.Specifier == specifier && locked.Commit != "" &&
+		!inst.refresh[name] && !inst.refresh[configs.All] {
+		return &directPackage{specifier: specifier, response: locked.Response(), dependencies: locked.Dependencies}, nil
+	}
+
+	var commit, treeDir string
+	var err error
+	if inst.offline {
+		refs := source.Refs()
+		if refs == nil {
+			refs = []string{"HEAD"}
+		}
+		commit, treeDir, err = utils.StoreLookup(source.URL, refs)
+	} else {
+		// Branches move, so the repository is always fetched before resolving the ref
+		commit, treeDir, err = utils.StoreSync(source.URL, source.Refs(), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies, err := utils.ReadPackageDependencies(treeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	fallback := source.Ref
+	if fallback == "" {
+		fallback = commit[:7]
+	}
+	return &directPackage{
+		specifier: specifier,
+		response: &utils.Response{
+			Key:    name,
+			Data:   utils.Data{GithubURL: source.URL, Version: utils.ReadPackageVersion(treeDir, fallback), Ref: source.Ref},
+			Commit: commit,
+		},
+		dependencies: dependencies,
+	}, nil
+}
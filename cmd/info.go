@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nep/configs"
+	"nep/registry"
+	"nep/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var infoJSON bool
+
+// packageInfo is everything nep info reports, as printed by --json.
+type packageInfo struct {
+	// Metadata is the registry response for the version shown
+	Metadata     *utils.Response   `json:"metadata"`
+	Versions     []string          `json:"versions"`
+	Dependencies map[string]string `json:"dependencies"`
+	// DependenciesError explains why the dependencies could not be read
+	DependenciesError string         `json:"dependenciesError,omitempty"`
+	Installed         *installedInfo `json:"installed"`
+}
+
+// installedInfo describes the copy of a package in the current project.
+type installedInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	Path    string `json:"path"`
+	// Type is "dependency" or "dev", empty when the package is only a transitive dependency
+	Type string `json:"type,omitempty"`
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info <package>[::version]",
+	Short: "Show the registry metadata of a package",
+	Long: `Show what the registry knows about a package: its repository, language scan,
+published versions and the dependencies it declares, along with whether it is
+installed in the current project. Without a version the latest release is
+shown; a version range shows the highest matching release.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, constraint := utils.SplitPackageArg(args[0])
+		if utils.IsSourceSpecifier(constraint) {
+			exitWithError(fmt.Errorf("%s is installed from source, nep info only shows registry packages", args[0]))
+		}
+
+		// The installed copy is only looked up when run inside a project
+		projectPath, err := currentProject()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		reg, err := registry.Load(projectPath, registryOptions())
+		if err != nil {
+			exitWithError(err)
+		}
+
+		info, err := lookupPackageInfo(reg, projectPath, name, constraint)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if infoJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(info); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+		printPackageInfo(info)
+	},
+}
+
+func lookupPackageInfo(reg registry.Registry, projectPath, name, constraint string) (*packageInfo, error) {
+	responseData, err := registry.Resolve(reg, name, constraint)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := reg.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+	info := &packageInfo{Metadata: responseData, Versions: utils.SortVersions(versions)}
+
+	lock := &utils.Lockfile{Packages: map[string]utils.LockedPackage{}}
+	if projectPath != "" {
+		if lock, err = utils.ReadLockfile(projectPath); err != nil {
+			return nil, err
+		}
+		info.Installed = installedPackage(projectPath, name)
+	}
+
+	// Packages without dependencies in their metadata declare them in their
+	// repository, which is only known here when the version is locked
+	version := strings.TrimPrefix(responseData.Data.Version, "v")
+	if dependencies := responseData.Data.Dependencies; dependencies != nil {
+		info.Dependencies = dependencies
+	} else if locked, ok := lock.Packages[name]; ok && strings.TrimPrefix(locked.Version, "v") == version {
+		info.Dependencies = locked.Dependencies
+		if info.Dependencies == nil {
+			info.Dependencies = map[string]string{}
+		}
+	} else {
+		info.DependenciesError = "the registry does not list them and this version is not installed"
+	}
+
+	return info, nil
+}
+
+// installedPackage returns the copy of a package in nebpack/, or nil.
+func installedPackage(projectPath, name string) *installedInfo {
+	packageDir := filepath.Join(projectPath, configs.FolderName, name)
+	saved, err := utils.ReadSavedResponse(packageDir)
+	if err != nil {
+		return nil
+	}
+
+	installed := &installedInfo{
+		Version: strings.TrimPrefix(saved.Data.Version, "v"),
+		Commit:  saved.Commit,
+		Path:    packageDir,
+	}
+	if roots, dev, err := readDependencies(projectPath); err == nil {
+		if _, ok := roots[name]; ok {
+			installed.Type = "dependency"
+			if dev[name] {
+				installed.Type = "dev"
+			}
+		}
+	}
+	return installed
+}
+
+func printPackageInfo(info *packageInfo) {
+	data := info.Metadata.Data
+	fmt.Printf("%s@%s\n", info.Metadata.Key, strings.TrimPrefix(data.Version, "v"))
+	if data.Description != "" {
+		fmt.Printf("  %s\n", data.Description)
+	}
+	fmt.Println()
+
+	lua := yesNo(data.IsLua)
+	if data.ScanResponse.Lua != "" {
+		lua += " (" + data.ScanResponse.Lua + " Lua)"
+	}
+	fmt.Printf("  Repository:    %s\n", data.GithubURL)
+	if data.Ref != "" {
+		fmt.Printf("  Ref:           %s\n", data.Ref)
+	}
+	fmt.Printf("  Lua:           %s\n", lua)
+	fmt.Printf("  Rockspec:      %s\n", yesNo(data.HasRockspec))
+	fmt.Printf("  Versions:      %s\n", strings.Join(info.Versions, ", "))
+
+	switch {
+	case info.DependenciesError != "":
+		fmt.Printf("  Dependencies:  unknown, %s\n", info.DependenciesError)
+	case len(info.Dependencies) == 0:
+		fmt.Println("  Dependencies:  none")
+	default:
+		fmt.Println("  Dependencies:")
+		for _, dep := range utils.SortedKeys(info.Dependencies) {
+			fmt.Printf("    %s %s\n", dep, info.Dependencies[dep])
+		}
+	}
+
+	if info.Installed == nil {
+		fmt.Println("  Installed:     no")
+		return
+	}
+	installed := info.Installed
+	detail := installedDetail(installed.Version, installed.Commit)
+	switch installed.Type {
+	case "dependency":
+		detail += ", as a dependency"
+	case "dev":
+		detail += ", as a dev dependency"
+	default:
+		detail += ", as a transitive dependency"
+	}
+	fmt.Printf("  Installed:     %s in %s\n", strings.TrimPrefix(detail, "installed "), installed.Path)
+}
+
+func init() {
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false, "Print the metadata as JSON")
+	rootCmd.AddCommand(infoCmd)
+}
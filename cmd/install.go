@@ -1,21 +1,24 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
-	"sync"
+	"runtime"
 
+	"nep/registry"
 	"nep/utils"
 
-	"github.com/go-git/go-git/v5"
 	"github.com/spf13/cobra"
 )
 
 var (
 	asynchronous bool
-	mu           sync.Mutex
+	jobs         int
+	dryRun       bool
+	offline      bool
+	saveDev      bool
+	production   bool
 )
 
 var installCmd = &cobra.Command{
@@ -23,129 +26,133 @@ var installCmd = &cobra.Command{
 	Aliases: []string{"i"},
 	Short:   "Install packages",
 	Run: func(cmd *cobra.Command, args []string) {
+		installPackages(utils.Prepare(true, path), args)
+	},
+}
 
-		projectPath := utils.Prepare(true, path)
+// installPackages installs the packages named in args, or every configured
+// dependency when args is empty, into the project at projectPath.
+func installPackages(projectPath string, args []string) {
+	folderPath := utils.GetFolder(projectPath)
 
-		folderPath := utils.GetFolder(projectPath)
+	lock, err := utils.ReadLockfile(projectPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
+	roots, dev, err := readDependencies(projectPath)
+	if err != nil {
 		if len(args) == 0 {
-			// Use ReadConfig to get the dependencies
-			results, err := utils.ReadConfig(projectPath, [][]string{{"dependencies"}})
-			if err != nil {
-				fmt.Printf("Error: Failed to read dependencies from config: %v\n", err)
-				os.Exit(1)
-			}
-
-			if len(results) == 0 || results[0] == nil {
-				return
-			}
-
-			// Assert and convert the result to map[string]interface{}
-			dependenciesInterface, ok := results[0].(map[string]interface{})
-			if !ok {
-				fmt.Println("Error: Dependencies in config are not in the expected format")
-				os.Exit(1)
-			}
-
-			// Convert to args format
-			for pkg, version := range dependenciesInterface {
-				versionStr, ok := version.(string)
-				if !ok {
-					fmt.Printf("Warning: Invalid version format for dependency %s: %v\n", pkg, version)
-					continue
-				}
-
-				versionStr = strings.TrimPrefix(versionStr, "v")
-				newArg := fmt.Sprintf("%s::%s", pkg, versionStr)
-				args = append(args, newArg)
-			}
-
-			if len(args) == 0 {
-				fmt.Println("No valid dependencies found to install")
-				return
-			}
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
+		roots, dev = map[string]string{}, map[string]bool{}
+	}
 
-		if asynchronous {
-			var wg sync.WaitGroup
-			for _, pkg := range args {
-				wg.Add(1)
-				go func(pkg string) {
-					defer wg.Done()
-					installPackage(pkg, projectPath, folderPath)
-				}(pkg)
-			}
-			wg.Wait()
-		} else {
-			for _, pkg := range args {
-				installPackage(pkg, projectPath, folderPath)
+	// Packages named on the command line are added to, or replace, the configured ones
+	for _, pkg := range args {
+		name, constraint := utils.SplitPackageArg(pkg)
+		roots[name] = constraint
+		dev[name] = saveDev
+	}
+
+	// Dev-only packages are left out of production installs
+	if production {
+		for name := range roots {
+			if dev[name] {
+				delete(roots, name)
 			}
 		}
-	},
-}
+	}
 
-func installPackage(pkg, projectPath, folderPath string) {
-	// Fetch data from API
-	responseData, err := utils.FetchPackageData(pkg)
-	if err != nil {
-		fmt.Printf("Failed to fetch data from API for %s: %s\n", pkg, err)
+	if len(roots) == 0 {
+		fmt.Println("No valid dependencies found to install")
 		return
 	}
 
-	// Extract package name and version
-	parts := strings.Split(pkg, "::")
-	packageName := parts[0]
+	reg, err := registry.Load(projectPath, registryOptions())
+	if err != nil {
+		exitWithInstallError(err)
+	}
 
-	// Create a new directory for the package inside FolderName
-	packageDir := filepath.Join(folderPath, packageName)
+	inst := newInstaller(projectPath, folderPath, lock, reg)
+	inst.offline = offline
+	inst.jobs = jobs
+	inst.dev = dev
+	inst.production = production
 
-	// Ensure the directory exists or create it
-	if err := os.MkdirAll(packageDir, 0755); err != nil {
-		fmt.Printf("Failed to create directory %s: %s\n", packageDir, err)
+	if dryRun {
+		if err := inst.resolve(roots); err != nil {
+			exitWithInstallError(err)
+		}
+		inst.printPlan()
 		return
 	}
 
-	// Clone GitHub repository into the package directory
-	options := &git.CloneOptions{
-		URL:      responseData.Data.GithubURL,
-		Progress: os.Stdout,
-	}
-	_, err = git.PlainClone(packageDir, false, options)
-	if err != nil {
-		fmt.Printf("Failed to clone %s: %s\n", pkg, err)
-		return
+	if err := inst.run(roots); err != nil {
+		exitWithInstallError(err)
 	}
 
-	// Save API response to file inside the package directory
-	if err := utils.SaveResponseToFile(responseData, packageDir); err != nil {
-		fmt.Printf("Failed to save API response JSON for %s: %s\n", pkg, err)
+	if production {
+		inst.removeUnused()
 	}
+}
 
-	fmt.Printf("Successfully cloned %s into %s\n", pkg, packageDir)
+// readDependencies reads the dependencies and devDependencies maps from the
+// project config, merged into one set of roots. dev marks the roots that are
+// only development dependencies; a package listed in both is a regular one.
+func readDependencies(projectPath string) (map[string]string, map[string]bool, error) {
+	results, err := utils.ReadConfig(projectPath, [][]string{{}})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read dependencies from config: %v", err)
+	}
+	config, _ := results[0].(map[string]interface{})
 
-	name := ""
+	roots := map[string]string{}
+	dev := map[string]bool{}
+	for _, key := range []string{"devDependencies", "dependencies"} {
+		if config[key] == nil {
+			continue
+		}
 
-	if responseData.Key == "" {
-		name = packageName
-	} else {
-		name = responseData.Key
-	}
+		// Assert and convert the result to map[string]interface{}
+		dependenciesInterface, ok := config[key].(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("%s in config are not in the expected format", key)
+		}
 
-	updates := []utils.UpdatePath{
-		{Path: []string{"dependencies", name}, Value: responseData.Data.Version},
+		for pkg, version := range dependenciesInterface {
+			versionStr, ok := version.(string)
+			if !ok {
+				fmt.Printf("Warning: Invalid version format for dependency %s: %v\n", pkg, version)
+				continue
+			}
+			roots[pkg] = versionStr
+			dev[pkg] = key == "devDependencies"
+		}
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
+	return roots, dev, nil
+}
 
-	err = utils.UpdateConfig(projectPath, updates)
-	if err != nil {
-		fmt.Println("Error updating config:", err)
+// exitWithInstallError is exitWithError for install, which can also run from the package store.
+func exitWithInstallError(err error) {
+	printError(err)
+	if !offline && errors.Is(err, registry.ErrRegistryUnavailable) {
+		fmt.Fprintln(os.Stderr, "Run nep install --offline to install from the lockfile and the package store")
 	}
+	os.Exit(1)
 }
 
 func init() {
 	installCmd.Flags().BoolVarP(&asynchronous, "asynchronous", "a", false, "Install packages in parallel")
+	installCmd.Flags().MarkDeprecated("asynchronous", "packages are always installed in parallel, use --jobs to limit them")
+	installCmd.Flags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "Number of packages to install at the same time")
+	installCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Resolve dependencies and print the plan without installing")
+	installCmd.Flags().BoolVarP(&saveDev, "save-dev", "D", false, "Record the packages in devDependencies")
+	installCmd.Flags().BoolVar(&production, "production", false, "Skip packages only listed in devDependencies")
+	installCmd.Flags().BoolVar(&offline, "offline", false, "Install only from the lockfile and the local package store")
 	installCmd.Flags().StringVarP(&path, "path", "p", "", "Set project path")
 	rootCmd.AddCommand(installCmd)
 }
@@ -0,0 +1,705 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"nep/configs"
+	"nep/registry"
+	"nep/resolver"
+	"nep/utils"
+)
+
+// installer resolves the dependency graph of a project and installs every package in it.
+type installer struct {
+	projectPath string
+	folderPath  string
+	// stagePath is where packages are written before the transaction moves them
+	// into folderPath; it defaults to folderPath
+	stagePath string
+	lock      *utils.Lockfile
+	source    packageSource
+	// offline installs only from the lockfile, saved metadata and the package store
+	offline  bool
+	roots    map[string]string
+	solution *resolver.Solution
+	resolved map[string]*utils.LockedPackage
+	// changed lists the packages that were written to stagePath
+	changed  []string
+	failures []failure
+	// jobs is the number of packages installed at the same time
+	jobs int
+	// progress shows what each package is doing; nil outside of run
+	progress *utils.Progress
+	mu       sync.Mutex
+	// refresh lists packages whose locked versions are not preferred when resolving;
+	// configs.All refreshes every package
+	refresh map[string]bool
+	// dev marks the roots declared in devDependencies; production installs leave
+	// them out and keep their lock entries
+	dev        map[string]bool
+	production bool
+	// direct holds the packages installed from source specifiers, and pinned their versions
+	direct map[string]*directPackage
+	pinned map[string]string
+}
+
+func newInstaller(projectPath, folderPath string, lock *utils.Lockfile, reg registry.Registry) *installer {
+	inst := &installer{
+		projectPath: projectPath,
+		folderPath:  folderPath,
+		stagePath:   folderPath,
+		lock:        lock,
+		jobs:        runtime.NumCPU(),
+		roots:       map[string]string{},
+		resolved:    map[string]*utils.LockedPackage{},
+		refresh:     map[string]bool{},
+		dev:         map[string]bool{},
+		direct:      map[string]*directPackage{},
+		pinned:      map[string]string{},
+	}
+	inst.source = newDirectSource(inst, newRegistrySource(lock, reg))
+	return inst
+}
+
+// packageSource is where the installer gets versions, dependencies and metadata from.
+type packageSource interface {
+	resolver.Source
+	fetch(name, version string) (*utils.Response, error)
+}
+
+// resolve picks versions for roots and their dependencies without installing anything.
+func (inst *installer) resolve(roots map[string]string) error {
+	for name, constraint := range roots {
+		inst.roots[name] = constraint
+	}
+
+	if inst.offline {
+		inst.source = newDirectSource(inst, newOfflineSource(inst.lock, inst.folderPath))
+	}
+
+	if solution := inst.lockedSolution(); solution != nil {
+		inst.solution = solution
+		return nil
+	}
+
+	if err := inst.pinRoots(); err != nil {
+		return err
+	}
+
+	if inst.offline {
+		if missing := inst.missingRoots(); len(missing) > 0 {
+			return offlineError(missing)
+		}
+	}
+
+	r := resolver.New(inst.source)
+	r.Pinned = inst.pinned
+	for name, locked := range inst.lock.Packages {
+		if !inst.refresh[name] && !inst.refresh[configs.All] {
+			r.Preferred[name] = locked.Version
+		}
+	}
+
+	solution, err := r.Resolve(inst.roots)
+	if err != nil {
+		if !inst.offline && errors.Is(err, registry.ErrRegistryUnavailable) {
+			inst.progress.Log("Warning: %v\nFalling back to offline mode", err)
+			inst.offline = true
+			return inst.resolve(roots)
+		}
+
+		var conflict *resolver.ConflictError
+		if errors.As(err, &conflict) {
+			return fmt.Errorf("could not resolve dependencies:\n  %s", err)
+		}
+		return err
+	}
+
+	inst.solution = solution
+	return nil
+}
+
+// lockedSolution builds the solution straight from the lockfile when every root
+// is unchanged since the last install, avoiding any registry round trips.
+func (inst *installer) lockedSolution() *resolver.Solution {
+	if len(inst.refresh) > 0 {
+		return nil
+	}
+
+	solution := &resolver.Solution{Packages: map[string]*resolver.Package{}}
+	var queue []resolver.Requirement
+	var names []string
+	for _, name := range utils.SortedKeys(inst.roots) {
+		locked, ok := inst.lock.Packages[name]
+		if !ok || locked.Specifier != inst.roots[name] || locked.Commit == "" {
+			return nil
+		}
+		queue = append(queue, resolver.Requirement{Constraint: inst.roots[name]})
+		names = append(names, name)
+	}
+
+	for len(names) > 0 {
+		name, req := names[0], queue[0]
+		names, queue = names[1:], queue[1:]
+
+		locked, ok := inst.lock.Packages[name]
+		if !ok || locked.Commit == "" {
+			return nil
+		}
+		// Source specifiers were already compared against the locked specifier
+		if !utils.IsSourceSpecifier(req.Constraint) && !utils.Satisfies(locked.Version, req.Constraint) {
+			return nil
+		}
+		if pkg, ok := solution.Packages[name]; ok {
+			pkg.RequiredBy = append(pkg.RequiredBy, req)
+			continue
+		}
+
+		solution.Packages[name] = &resolver.Package{
+			Name:         name,
+			Version:      locked.Version,
+			Dependencies: locked.Dependencies,
+			RequiredBy:   []resolver.Requirement{req},
+		}
+		for _, dep := range utils.SortedKeys(locked.Dependencies) {
+			names = append(names, dep)
+			queue = append(queue, resolver.Requirement{Parent: name, ParentVersion: locked.Version, Constraint: locked.Dependencies[dep]})
+		}
+	}
+
+	return solution
+}
+
+// run installs roots as a single transaction: packages are staged, and only
+// once every one of them succeeded are they moved into place and the config
+// and lockfile updated.
+func (inst *installer) run(roots map[string]string) error {
+	tx, err := newTransaction(inst.projectPath, inst.folderPath)
+	if err != nil {
+		return err
+	}
+	inst.progress = utils.NewProgress("Installing packages")
+	inst.progress.Start()
+	defer inst.progress.Stop()
+
+	tx.beforeExit = inst.progress.Stop
+	stop := tx.rollbackOnInterrupt()
+	defer stop()
+
+	inst.stagePath = tx.stagePath
+	inst.install(roots)
+	inst.progress.Stop()
+	if len(inst.failures) > 0 {
+		tx.rollback()
+		return inst.failureError()
+	}
+
+	if err := tx.commit(inst.changed, inst.commit); err != nil {
+		return fmt.Errorf("failed to apply the install, no changes were made: %v", err)
+	}
+	return nil
+}
+
+// install resolves roots and installs every package of the resulting solution.
+func (inst *installer) install(roots map[string]string) {
+	if err := inst.resolve(roots); err != nil {
+		inst.fail("", err)
+		return
+	}
+
+	if inst.offline {
+		if missing := inst.missingPackages(); len(missing) > 0 {
+			inst.fail("", offlineError(missing))
+			return
+		}
+	}
+
+	names := inst.solution.Names()
+	results := make([]*utils.LockedPackage, len(names))
+	installAt := func(i int) {
+		locked, err := inst.installOne(inst.solution.Packages[names[i]])
+		if err != nil {
+			inst.progress.Set(names[i], utils.PhaseFailed, err.Error())
+			inst.fail(names[i], err)
+			return
+		}
+		results[i] = locked
+	}
+
+	// A fixed pool of workers takes packages off the queue until it is empty
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(1, min(inst.jobs, len(names))); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				installAt(i)
+			}
+		}()
+	}
+	for i := range names {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	// The registry can go away after resolving, so retry from the lockfile and the store
+	if err := inst.registryFailure(); err != nil && !inst.offline {
+		inst.progress.Log("Warning: %v\nFalling back to offline mode", err)
+		inst.offline = true
+		inst.failures = nil
+		inst.changed = nil
+		inst.install(roots)
+		return
+	}
+
+	for i, name := range names {
+		if results[i] != nil {
+			inst.resolved[name] = results[i]
+		}
+	}
+}
+
+// printPlan describes what install would do without touching the project.
+func (inst *installer) printPlan() {
+	fmt.Println("Resolved dependency graph:")
+	for _, name := range inst.solution.Names() {
+		pkg := inst.solution.Packages[name]
+
+		action := "install"
+		if locked, ok := inst.lock.Packages[name]; ok {
+			if locked.Version == pkg.Version {
+				action = "keep"
+			} else {
+				action = "change from " + locked.Version
+			}
+		}
+
+		var reasons []string
+		for _, req := range pkg.RequiredBy {
+			parent := "the project"
+			if req.Parent != "" {
+				parent = req.Parent + "@" + req.ParentVersion
+			}
+			reasons = append(reasons, fmt.Sprintf("%s (%s)", parent, utils.DescribeConstraint(req.Constraint)))
+		}
+
+		fmt.Printf("  %s@%s: %s, required by %s\n", name, pkg.Version, action, strings.Join(reasons, ", "))
+	}
+
+	for name := range inst.lock.Packages {
+		if _, ok := inst.solution.Packages[name]; !ok {
+			fmt.Printf("  %s: remove\n", name)
+		}
+	}
+}
+
+// failure is an error that kept a package, or the whole run when name is empty, from installing.
+type failure struct {
+	name string
+	err  error
+}
+
+func (inst *installer) fail(name string, err error) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	inst.failures = append(inst.failures, failure{name: name, err: err})
+}
+
+// registryFailure returns the first failure caused by an unreachable registry, if any.
+func (inst *installer) registryFailure() error {
+	for _, f := range inst.failures {
+		if errors.Is(f.err, registry.ErrRegistryUnavailable) {
+			return f.err
+		}
+	}
+	return nil
+}
+
+// failureError summarizes every failure of the run in a single error.
+func (inst *installer) failureError() error {
+	if len(inst.failures) == 1 && inst.failures[0].name == "" {
+		return inst.failures[0].err
+	}
+
+	sort.Slice(inst.failures, func(i, j int) bool {
+		return inst.failures[i].name < inst.failures[j].name
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "failed to install %d of %d packages, no changes were made:", len(inst.failures), len(inst.solution.Packages))
+	for _, f := range inst.failures {
+		fmt.Fprintf(&sb, "\n  - %s: %v", f.name, f.err)
+	}
+	return errors.New(sb.String())
+}
+
+// installOne installs a single resolved package, preferring the lockfile when it still applies.
+func (inst *installer) installOne(pkg *resolver.Package) (*utils.LockedPackage, error) {
+	specifier := inst.specifierFor(pkg)
+
+	// A package from a branch keeps its version while its commit moves on
+	direct, isDirect := inst.direct[pkg.Name]
+	if locked, ok := inst.lock.Packages[pkg.Name]; ok && locked.Commit != "" && locked.Version == pkg.Version &&
+		(!isDirect || direct.response.Commit == locked.Commit) {
+		locked.Specifier = specifier
+		return inst.installLocked(pkg.Name, locked)
+	}
+
+	locked, err := inst.installVersion(pkg)
+	if err != nil {
+		return nil, err
+	}
+	locked.Specifier = specifier
+	return locked, nil
+}
+
+// specifierFor returns the value recorded for a package: the user's range for
+// root packages, or the concrete version when an exact version was requested.
+func (inst *installer) specifierFor(pkg *resolver.Package) string {
+	constraint, isRoot := inst.roots[pkg.Name]
+	if !isRoot {
+		if len(pkg.RequiredBy) > 0 {
+			return pkg.RequiredBy[0].Constraint
+		}
+		return ""
+	}
+	if constraint == "" || utils.IsExactVersion(constraint) {
+		return pkg.Version
+	}
+	return constraint
+}
+
+// installLocked installs a package at the exact commit recorded in nebula-lock.json.
+func (inst *installer) installLocked(name string, locked utils.LockedPackage) (*utils.LockedPackage, error) {
+	// Nothing to do if the package is already installed from the locked commit
+	if commit, err := utils.InstalledCommit(filepath.Join(inst.folderPath, name)); err == nil && commit == locked.Commit {
+		// Lockfiles written before integrity hashes existed are completed from the pristine store copy
+		if locked.Integrity == "" {
+			if treeDir, ok := utils.StoreTreeDir(locked.GithubURL, locked.Commit); ok {
+				locked.Integrity, _ = utils.TreeHash(treeDir)
+			}
+		}
+		inst.progress.Set(name, utils.PhaseDone, "up to date at "+shortCommit(locked.Commit))
+		return &locked, nil
+	}
+
+	integrity, err := inst.populate(name, locked.GithubURL, []string{locked.Commit}, locked.Response())
+	if err != nil {
+		return nil, err
+	}
+	if locked.Integrity != "" && integrity != locked.Integrity {
+		return nil, fmt.Errorf("integrity check failed for %s@%s: expected %s, got %s", name, locked.Version, locked.Integrity, integrity)
+	}
+	locked.Integrity = integrity
+
+	inst.progress.Set(name, utils.PhaseDone, installedDetail(locked.Version, locked.Commit))
+	return &locked, nil
+}
+
+// installVersion fetches the registry data for a resolved version and installs it.
+func (inst *installer) installVersion(pkg *resolver.Package) (*utils.LockedPackage, error) {
+	id := pkg.Name + "::" + pkg.Version
+
+	inst.progress.Set(pkg.Name, utils.PhaseResolving, pkg.Version)
+	responseData, err := inst.source.fetch(pkg.Name, pkg.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data from API for %s: %w", id, err)
+	}
+
+	// Copy the response so the commit can be recorded without touching the shared cache
+	saved := *responseData
+	var integrity string
+	if direct, ok := inst.direct[pkg.Name]; ok && direct.dir != "" {
+		integrity, err = inst.link(pkg.Name, direct.dir, &saved)
+	} else {
+		integrity, err = inst.populate(pkg.Name, responseData.Data.GithubURL, storeRefs(responseData), &saved)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	inst.progress.Set(pkg.Name, utils.PhaseDone, installedDetail(pkg.Version, saved.Commit))
+
+	return &utils.LockedPackage{
+		Key:          responseData.Key,
+		GithubURL:    responseData.Data.GithubURL,
+		Version:      responseData.Data.Version,
+		Commit:       saved.Commit,
+		Integrity:    integrity,
+		Dependencies: pkg.Dependencies,
+	}, nil
+}
+
+// populate fills the package directory from the package store, fetching the
+// first of refs into the store if needed, and saves responseData next to it.
+// It returns the integrity hash of the installed tree.
+func (inst *installer) populate(name, url string, refs []string, responseData *utils.Response) (string, error) {
+	fetch := utils.StoreFetch
+	if inst.offline {
+		fetch = func(url string, refs []string, _ io.Writer) (string, string, error) {
+			return utils.StoreLookup(url, refs)
+		}
+	}
+
+	commit, treeDir, err := fetch(url, refs, inst.progress.Writer(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %s", name, err)
+	}
+
+	responseData.Commit = commit
+	return inst.link(name, treeDir, responseData)
+}
+
+// link copies treeDir into the package directory, saves responseData next to it
+// and returns the integrity hash of the result.
+func (inst *installer) link(name, treeDir string, responseData *utils.Response) (string, error) {
+	inst.progress.Set(name, utils.PhaseLinking, "")
+
+	// Create a new directory for the package inside FolderName
+	packageDir := filepath.Join(inst.stagePath, name)
+	if err := prepareDir(packageDir); err != nil {
+		return "", err
+	}
+
+	if err := utils.LinkTree(treeDir, packageDir); err != nil {
+		os.RemoveAll(packageDir)
+		return "", fmt.Errorf("failed to install %s from the package store: %s", name, err)
+	}
+
+	integrity, err := utils.TreeHash(packageDir)
+	if err != nil {
+		return "", err
+	}
+
+	// Save API response to file inside the package directory
+	if err := utils.SaveResponseToFile(responseData, packageDir); err != nil {
+		inst.progress.Log("Failed to save API response JSON for %s: %s", name, err)
+	}
+
+	inst.markChanged(name)
+	return integrity, nil
+}
+
+func (inst *installer) markChanged(name string) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	inst.changed = append(inst.changed, name)
+}
+
+// commit records the root packages in the config and every installed package in the lockfile.
+// Lock entries for packages that are no longer part of the graph are removed.
+func (inst *installer) commit() error {
+	var updates []utils.UpdatePath
+	for _, name := range utils.SortedKeys(inst.roots) {
+		locked, ok := inst.resolved[name]
+		if !ok {
+			continue
+		}
+		key := name
+		if locked.Key != "" {
+			key = locked.Key
+		}
+
+		// A package lives in exactly one of the two sets
+		set, other := "dependencies", "devDependencies"
+		if inst.dev[name] {
+			set, other = other, set
+		}
+		updates = append(updates,
+			utils.UpdatePath{Path: []string{set, key}, Value: locked.Specifier},
+			utils.UpdatePath{Path: []string{other, key}, Value: configs.RemoveMarker})
+	}
+
+	if len(updates) > 0 {
+		if err := utils.UpdateConfig(inst.projectPath, updates); err != nil {
+			return err
+		}
+	}
+
+	lockUpdates := map[string]*utils.LockedPackage{}
+	for name, locked := range inst.resolved {
+		lockUpdates[name] = locked
+	}
+	// Production installs leave the dev-only packages in the lockfile
+	packages := map[string]utils.LockedPackage{}
+	for name, locked := range inst.lock.Packages {
+		if _, ok := inst.resolved[name]; !ok {
+			if len(inst.failures) == 0 && !inst.production {
+				lockUpdates[name] = nil
+			} else {
+				packages[name] = locked
+			}
+		}
+	}
+	for name, locked := range inst.resolved {
+		packages[name] = *locked
+	}
+
+	// Mark the dev-only packages so the lockfile tells which ones may be missing from nebpack/
+	runtime := runtimePackages(inst.roots, inst.dev, packages)
+	for name, locked := range packages {
+		locked.Dev = !runtime[name]
+		lockUpdates[name] = &locked
+	}
+
+	return utils.UpdateLockfile(inst.projectPath, lockUpdates)
+}
+
+// runtimePackages returns the packages reachable from the roots that are not
+// dev dependencies, following the dependencies recorded in packages.
+func runtimePackages(roots map[string]string, dev map[string]bool, packages map[string]utils.LockedPackage) map[string]bool {
+	runtime := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if runtime[name] {
+			return
+		}
+		runtime[name] = true
+		for dep := range packages[name].Dependencies {
+			visit(dep)
+		}
+	}
+	for name := range roots {
+		if !dev[name] {
+			visit(name)
+		}
+	}
+	return runtime
+}
+
+// removeUnused deletes the installed packages that are locked but not part of
+// the solution, so that dev-only packages are not shipped with a production install.
+func (inst *installer) removeUnused() {
+	if inst.solution == nil || len(inst.failures) > 0 {
+		return
+	}
+	for _, name := range utils.SortedKeys(inst.lock.Packages) {
+		if _, ok := inst.solution.Packages[name]; ok {
+			continue
+		}
+		packageDir := filepath.Join(inst.folderPath, name)
+		if _, err := os.Stat(packageDir); err != nil {
+			continue
+		}
+		if err := os.RemoveAll(packageDir); err != nil {
+			fmt.Printf("Error removing %s: %v\n", name, err)
+			continue
+		}
+		fmt.Printf("Removed %s, which is not needed in production\n", name)
+	}
+}
+
+// registrySource answers the resolver's questions through a registry.
+// Dependencies are taken from the lockfile or the registry metadata when
+// available, and otherwise read from the package's tree in the store.
+type registrySource struct {
+	registry  registry.Registry
+	lock      *utils.Lockfile
+	responses map[string]*utils.Response
+	mu        sync.Mutex
+}
+
+func newRegistrySource(lock *utils.Lockfile, reg registry.Registry) *registrySource {
+	return &registrySource{
+		registry:  reg,
+		lock:      lock,
+		responses: map[string]*utils.Response{},
+	}
+}
+
+func (s *registrySource) Versions(name string) ([]string, error) {
+	versions, err := s.registry.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s has no versions in %s", registry.ErrPackageNotFound, name, s.registry.Name())
+	}
+	return versions, nil
+}
+
+func (s *registrySource) Dependencies(name, version string) (map[string]string, error) {
+	if locked, ok := s.lock.Packages[name]; ok && locked.Commit != "" && locked.Version == version {
+		return locked.Dependencies, nil
+	}
+
+	responseData, err := s.fetch(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if responseData.Data.Dependencies != nil {
+		return responseData.Data.Dependencies, nil
+	}
+
+	_, treeDir, err := utils.StoreFetch(responseData.Data.GithubURL, utils.VersionRefs(responseData.Data), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s@%s: %s", name, version, err)
+	}
+
+	dependencies, err := utils.ReadPackageDependencies(treeDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dependencies of %s@%s: %s", name, version, err)
+	}
+	return dependencies, nil
+}
+
+// fetch returns the registry data for an exact version, fetching it at most once.
+func (s *registrySource) fetch(name, version string) (*utils.Response, error) {
+	key := name + "@" + version
+
+	s.mu.Lock()
+	responseData, ok := s.responses[key]
+	s.mu.Unlock()
+	if ok {
+		return responseData, nil
+	}
+
+	responseData, err := s.registry.Fetch(name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.responses[key] = responseData
+	s.mu.Unlock()
+	return responseData, nil
+}
+
+// prepareDir replaces any previous install of a package with an empty directory.
+func prepareDir(packageDir string) error {
+	if err := os.RemoveAll(packageDir); err != nil {
+		return fmt.Errorf("failed to remove previous install in %s: %s", packageDir, err)
+	}
+	if err := os.MkdirAll(packageDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %s", packageDir, err)
+	}
+	return nil
+}
+
+// shortCommit abbreviates a commit SHA the way git does.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// installedDetail describes an installed package for the progress view.
+func installedDetail(version, commit string) string {
+	if commit == "" {
+		return "installed " + version
+	}
+	return fmt.Sprintf("installed %s (%s)", version, shortCommit(commit))
+}
@@ -16,34 +16,21 @@ var listCmd = &cobra.Command{
 		// Change working directory if path is set
 		projectPath := utils.Prepare(false, path)
 
-		keys := [][]string{
-			{"dependencies"},
-		}
-
-		results, err := utils.ReadConfig(projectPath, keys)
+		dependencies, dev, err := readDependencies(projectPath)
 		if err != nil {
 			fmt.Printf("Error reading config: %v\n", err)
 			os.Exit(1)
 		}
 
-		// Convert the result to a map
-		dependencies := map[string]string{}
-		if len(results) > 0 {
-			depMap, ok := results[0].(map[string]interface{})
-			if !ok {
-				fmt.Println("Error: dependencies are not in the expected format")
-				os.Exit(1)
-			}
-
-			for pkg, version := range depMap {
-				dependencies[pkg] = fmt.Sprintf("%v", version)
-			}
-		}
-
-		headers := []string{"Package", "Version"}
+		headers := []string{"Package", "Version", "Type"}
 		rows := [][]string{}
-		for pkg, version := range dependencies {
-			rows = append(rows, []string{pkg, version})
+		for _, pkg := range utils.SortedKeys(dependencies) {
+			version := dependencies[pkg]
+			kind := "dependency"
+			if dev[pkg] {
+				kind = "dev"
+			}
+			rows = append(rows, []string{pkg, version, kind})
 		}
 
 		// Display table
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"nep/registry"
+	"nep/utils"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gitLogin    bool
+	gitUsername string
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login [registry | --git host]",
+	Short: "Store a token for a registry or git host",
+	Long: `Store the token nep sends to a registry as Bearer authorization. The
+registry defaults to the one the project installs from. The token is read
+from a prompt, or from stdin when it is not a terminal:
+
+  echo "$TOKEN" | nep login https://registry.studio.internal
+
+With --git the token is used instead to clone private package repositories
+over HTTPS from a git host, such as a personal access token:
+
+  nep login --git git.studio.internal --username build
+
+Tokens are kept in credentials.json in the nep config directory, readable
+only by you. For CI jobs NEP_TOKEN can hold a token instead, but as it does
+not name a registry it is only sent when the project uses a single registry
+besides the default one and that registry has no stored token. Git clones also
+use ~/.netrc, ssh-agent and the SSH keys set in the "git" section of the
+nep config.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		target, err := credentialsTarget(args)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		token, err := readToken(target)
+		if errors.Is(err, utils.ErrPromptCancelled) {
+			fmt.Println("Login cancelled")
+			return
+		} else if err != nil {
+			exitWithError(err)
+		}
+		if token == "" {
+			exitWithError(fmt.Errorf("no token given"))
+		}
+
+		credentials, err := utils.ReadCredentials()
+		if err != nil {
+			exitWithError(err)
+		}
+		if gitLogin {
+			credentials.SetHost(target, gitUsername, token)
+		} else {
+			credentials.SetRegistry(target, token)
+		}
+		if err := credentials.Write(); err != nil {
+			exitWithError(err)
+		}
+
+		if !gitLogin {
+			// Responses cached without the token may differ from what the registry now shows
+			registry.OpenCache(false).Clear(target)
+		}
+
+		fmt.Printf("Logged in to %s\n", target)
+	},
+}
+
+// credentialsTarget returns what a login applies to: the git host given with
+// --git, the registry argument, or else the unscoped registry the current
+// project installs from.
+func credentialsTarget(args []string) (string, error) {
+	if gitLogin {
+		if len(args) == 0 {
+			return "", fmt.Errorf("--git needs the host name, for example nep login --git git.studio.internal")
+		}
+		host := args[0]
+		if strings.Contains(host, "://") {
+			return "", fmt.Errorf("--git takes a host name such as git.studio.internal, not a URL")
+		}
+		return strings.ToLower(host), nil
+	}
+
+	location := ""
+	if len(args) > 0 {
+		location = args[0]
+	} else {
+		projectPath, err := currentProject()
+		if err != nil {
+			return "", err
+		}
+		sources, err := registry.Sources(projectPath, registryLocation)
+		if err != nil {
+			return "", err
+		}
+		for _, source := range sources {
+			if len(source.Scopes) == 0 {
+				location = source.URL
+				break
+			}
+		}
+	}
+
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return "", fmt.Errorf("%s is not an HTTP registry, only those accept a login", location)
+	}
+	return strings.TrimRight(location, "/"), nil
+}
+
+// readToken prompts for the token, or reads the first line of stdin when it is not a terminal.
+func readToken(target string) (string, error) {
+	if isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		token, err := utils.SecretInput("Token for " + target)
+		return strings.TrimSpace(token), err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read token from stdin: %v", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func init() {
+	loginCmd.Flags().BoolVar(&gitLogin, "git", false, "Store an HTTPS token for cloning from a git host")
+	loginCmd.Flags().StringVar(&gitUsername, "username", "", "User name sent with the token of a git host")
+	rootCmd.AddCommand(loginCmd)
+}
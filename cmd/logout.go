@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"nep/registry"
+	"nep/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout [registry | --git host]",
+	Short: "Remove the stored token of a registry or git host",
+	Long: `Remove the token stored by nep login, along with the metadata cached
+from the registry. The registry defaults to the one the project installs from.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		target, err := credentialsTarget(args)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		credentials, err := utils.ReadCredentials()
+		if err != nil {
+			exitWithError(err)
+		}
+		removed := false
+		if gitLogin {
+			removed = credentials.RemoveHost(target)
+		} else {
+			removed = credentials.RemoveRegistry(target)
+		}
+		if !removed {
+			fmt.Printf("Not logged in to %s\n", target)
+			return
+		}
+		if err := credentials.Write(); err != nil {
+			exitWithError(err)
+		}
+		if !gitLogin {
+			registry.OpenCache(false).Clear(target)
+		}
+
+		fmt.Printf("Logged out of %s\n", target)
+	},
+}
+
+func init() {
+	logoutCmd.Flags().BoolVar(&gitLogin, "git", false, "Remove the token of a git host")
+	rootCmd.AddCommand(logoutCmd)
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"nep/configs"
+	"nep/utils"
+)
+
+// offlineSource answers the resolver's questions from what is already on disk:
+// the lockfile, the metadata saved inside nebpack/ and the package store.
+type offlineSource struct {
+	lock       *utils.Lockfile
+	folderPath string
+}
+
+func newOfflineSource(lock *utils.Lockfile, folderPath string) *offlineSource {
+	return &offlineSource{lock: lock, folderPath: folderPath}
+}
+
+// known returns every piece of metadata available locally for a package.
+func (s *offlineSource) known(name string) []*utils.Response {
+	var responses []*utils.Response
+	if locked, ok := s.lock.Packages[name]; ok && locked.Commit != "" {
+		responses = append(responses, locked.Response())
+	}
+	if saved, err := utils.ReadSavedResponse(filepath.Join(s.folderPath, name)); err == nil && saved.Data.Version != "" {
+		responses = append(responses, saved)
+	}
+	return responses
+}
+
+func (s *offlineSource) Versions(name string) ([]string, error) {
+	var versions []string
+	seen := map[string]bool{}
+	for _, responseData := range s.known(name) {
+		if !seen[responseData.Data.Version] {
+			seen[responseData.Data.Version] = true
+			versions = append(versions, responseData.Data.Version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%s is not available offline: it is neither in %s.json nor installed", name, configs.LockFileName)
+	}
+	return versions, nil
+}
+
+func (s *offlineSource) Dependencies(name, version string) (map[string]string, error) {
+	// The lockfile always records the full set of dependencies
+	if locked, ok := s.lock.Packages[name]; ok && locked.Commit != "" && locked.Version == version {
+		return locked.Dependencies, nil
+	}
+
+	responseData, err := s.fetch(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if responseData.Data.Dependencies != nil {
+		return responseData.Data.Dependencies, nil
+	}
+
+	if treeDir, ok := utils.StoreTreeDir(responseData.Data.GithubURL, responseData.Commit); ok {
+		return utils.ReadPackageDependencies(treeDir)
+	}
+	return utils.ReadPackageDependencies(filepath.Join(s.folderPath, name))
+}
+
+func (s *offlineSource) fetch(name, version string) (*utils.Response, error) {
+	for _, responseData := range s.known(name) {
+		if responseData.Data.Version == version {
+			return responseData, nil
+		}
+	}
+	return nil, fmt.Errorf("no metadata for %s@%s is available offline", name, version)
+}
+
+// missingRoots lists the root dependencies that cannot be satisfied from local data.
+func (inst *installer) missingRoots() []string {
+	var missing []string
+	for _, name := range utils.SortedKeys(inst.roots) {
+		constraint := inst.roots[name]
+		if utils.IsSourceSpecifier(constraint) {
+			// Already pinned from the store or a local directory
+			continue
+		}
+		versions, err := inst.source.Versions(name)
+		if err != nil {
+			missing = append(missing, err.Error())
+			continue
+		}
+
+		matched := false
+		for _, version := range versions {
+			if utils.Satisfies(version, constraint) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			missing = append(missing, fmt.Sprintf("%s %s: no matching version is available offline (known: %s)",
+				name, utils.DescribeConstraint(constraint), strings.Join(versions, ", ")))
+		}
+	}
+	return missing
+}
+
+// missingPackages lists the resolved packages whose files are not available locally.
+func (inst *installer) missingPackages() []string {
+	var missing []string
+	for _, name := range inst.solution.Names() {
+		pkg := inst.solution.Packages[name]
+		if direct, ok := inst.direct[name]; ok && direct.dir != "" {
+			continue
+		}
+
+		responseData, err := inst.source.fetch(name, pkg.Version)
+		if err != nil {
+			missing = append(missing, err.Error())
+			continue
+		}
+
+		if responseData.Commit != "" {
+			if commit, err := utils.InstalledCommit(filepath.Join(inst.folderPath, name)); err == nil && commit == responseData.Commit {
+				continue
+			}
+		}
+
+		if _, _, err := utils.StoreLookup(responseData.Data.GithubURL, storeRefs(responseData)); err != nil {
+			missing = append(missing, fmt.Sprintf("%s@%s: %v", name, pkg.Version, err))
+		}
+	}
+	return missing
+}
+
+// offlineError formats everything that prevents an offline install.
+func offlineError(missing []string) error {
+	return fmt.Errorf("cannot install offline, missing:\n  - %s", strings.Join(missing, "\n  - "))
+}
+
+// storeRefs returns the refs to look up in the store for a package's metadata,
+// preferring the exact commit when it is known.
+func storeRefs(responseData *utils.Response) []string {
+	if responseData.Commit != "" {
+		return []string{responseData.Commit}
+	}
+	return utils.VersionRefs(responseData.Data)
+}
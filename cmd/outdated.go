@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"nep/registry"
+	"nep/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var outdatedJSON bool
+
+// outdatedRequests limits how many packages are looked up at the same time.
+const outdatedRequests = 8
+
+// outdatedPackage is a dependency with a newer release, as printed by --json.
+type outdatedPackage struct {
+	// Current is the installed version, empty when the package is not installed
+	Current string `json:"current"`
+	Wanted  string `json:"wanted"`
+	Latest  string `json:"latest"`
+	Range   string `json:"range"`
+	Type    string `json:"type"`
+}
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List dependencies with newer releases",
+	Long: `Check every entry of dependencies and devDependencies against the registry
+and list those with a newer release. Wanted is the highest version the declared
+range allows, which nep update installs; Latest is the highest version published.
+
+Packages installed from git or a local directory are not checked. The command
+exits with status 1 when a package is outdated, so CI can flag it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		projectPath := utils.Prepare(false, path)
+		folderPath := utils.GetFolder(projectPath)
+
+		dependencies, dev, err := readDependencies(projectPath)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		reg, err := registry.Load(projectPath, registryOptions())
+		if err != nil {
+			exitWithError(err)
+		}
+
+		var (
+			mu       sync.Mutex
+			wg       sync.WaitGroup
+			outdated = map[string]outdatedPackage{}
+			failures []failure
+		)
+		limit := make(chan struct{}, outdatedRequests)
+		for name, constraint := range dependencies {
+			if utils.IsSourceSpecifier(constraint) {
+				continue
+			}
+
+			wg.Add(1)
+			go func(name, constraint string) {
+				defer wg.Done()
+				limit <- struct{}{}
+				defer func() { <-limit }()
+
+				pkg, isOutdated, err := checkOutdated(reg, folderPath, name, constraint)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					failures = append(failures, failure{name: name, err: err})
+					return
+				}
+				if isOutdated {
+					pkg.Type = "dependency"
+					if dev[name] {
+						pkg.Type = "dev"
+					}
+					outdated[name] = pkg
+				}
+			}(name, constraint)
+		}
+		wg.Wait()
+
+		if outdatedJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(outdated); err != nil {
+				exitWithError(err)
+			}
+		} else if len(outdated) == 0 && len(failures) == 0 {
+			fmt.Println("All dependencies are up to date")
+		} else if len(outdated) == 0 {
+			fmt.Println("No outdated dependencies found")
+		} else {
+			headers := []string{"Package", "Current", "Wanted", "Latest", "Type"}
+			rows := [][]string{}
+			for _, name := range utils.SortedKeys(outdated) {
+				pkg := outdated[name]
+				current := pkg.Current
+				if current == "" {
+					current = "missing"
+				}
+				rows = append(rows, []string{name, current, pkg.Wanted, pkg.Latest, pkg.Type})
+			}
+			if err := utils.DisplayTable(headers, rows); err != nil {
+				exitWithError(err)
+			}
+		}
+
+		// Packages that could not be checked are reported after the others
+		sort.Slice(failures, func(i, j int) bool { return failures[i].name < failures[j].name })
+		for _, f := range failures {
+			fmt.Fprintf(os.Stderr, "Error checking %s: %v\n", f.name, f.err)
+		}
+
+		if len(outdated) > 0 || len(failures) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// checkOutdated compares the installed version of a dependency with the
+// versions published in the registry.
+func checkOutdated(reg registry.Registry, folderPath, name, constraint string) (outdatedPackage, bool, error) {
+	pkg := outdatedPackage{Range: constraint}
+
+	c, err := utils.ParseConstraint(constraint)
+	if err != nil {
+		return pkg, false, err
+	}
+	versions, err := reg.Versions(name)
+	if err != nil {
+		return pkg, false, err
+	}
+	sorted := utils.SortVersions(versions)
+	if len(sorted) == 0 {
+		return pkg, false, fmt.Errorf("%w: %s has no published versions", registry.ErrPackageNotFound, name)
+	}
+
+	pkg.Latest = strings.TrimPrefix(sorted[len(sorted)-1], "v")
+	if wanted, ok := c.MaxSatisfying(sorted); ok {
+		pkg.Wanted = strings.TrimPrefix(wanted, "v")
+	} else {
+		pkg.Wanted = "none"
+	}
+	if saved, err := utils.ReadSavedResponse(filepath.Join(folderPath, name)); err == nil {
+		pkg.Current = strings.TrimPrefix(saved.Data.Version, "v")
+	}
+
+	return pkg, pkg.Current != pkg.Wanted || pkg.Current != pkg.Latest, nil
+}
+
+func init() {
+	outdatedCmd.Flags().BoolVar(&outdatedJSON, "json", false, "Print the outdated packages as JSON")
+	rootCmd.AddCommand(outdatedCmd)
+}
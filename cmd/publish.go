@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"nep/configs"
+	"nep/registry"
+	"nep/utils"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/spf13/cobra"
+)
+
+var publishDryRun bool
+
+// packageNamePattern allows names such as "json" and scoped names such as "studio/json".
+var packageNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*(/[A-Za-z0-9][A-Za-z0-9._-]*)?$`)
+
+var publishCmd = &cobra.Command{
+	Use:   "publish",
+	Short: "Publish the project to the registry",
+	Long: `Submit the version in nebula-config.json to the registry. Before anything is
+sent, the config is checked for a name, version, license, main file and
+repository URL, and the version must be tagged (v1.2.3 or 1.2.3) and the tag
+pushed to the repository, since that is what installs will check out.
+
+With --dry-run the checks still run, and the request that would be sent is
+printed instead.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		projectPath := utils.Prepare(false, path)
+
+		publication, err := readPublication(projectPath)
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := checkPublishedTag(projectPath, publication); err != nil {
+			exitWithError(err)
+		}
+
+		reg, err := registry.Load(projectPath, registryOptions())
+		if err != nil {
+			exitWithError(err)
+		}
+		publisher, ok := reg.(registry.Publisher)
+		if !ok {
+			exitWithError(fmt.Errorf("%s does not accept published packages", reg.Name()))
+		}
+
+		if publishDryRun {
+			target, body, err := publisher.PublishRequest(publication)
+			if err != nil {
+				exitWithError(err)
+			}
+			fmt.Println(target)
+			fmt.Println(string(body))
+			fmt.Println("Dry run, nothing was published")
+			return
+		}
+
+		if err := publisher.Publish(publication); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Published %s@%s to %s\n", publication.Key, publication.Version, reg.Name())
+	},
+}
+
+// readPublication builds the publication from nebula-config.json, listing
+// every problem with the config at once.
+func readPublication(projectPath string) (*registry.Publication, error) {
+	results, err := utils.ReadConfig(projectPath, [][]string{{}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	config, _ := results[0].(map[string]interface{})
+
+	stringField := func(key string) string {
+		value, _ := config[key].(string)
+		return strings.TrimSpace(value)
+	}
+	publication := &registry.Publication{
+		Key:         stringField("name"),
+		Version:     strings.TrimPrefix(stringField("version"), "v"),
+		Description: stringField("description"),
+		Author:      stringField("author"),
+		License:     stringField("license"),
+		Main:        stringField("main"),
+		GithubURL:   repositoryURL(config["repository"]),
+	}
+
+	var problems []string
+	switch {
+	case publication.Key == "":
+		problems = append(problems, "name is missing")
+	case !packageNamePattern.MatchString(publication.Key):
+		problems = append(problems, fmt.Sprintf("name %q may only contain letters, digits, '.', '_' and '-', with an optional scope/ prefix", publication.Key))
+	}
+
+	switch {
+	case publication.Version == "":
+		problems = append(problems, "version is missing")
+	case !utils.IsExactVersion(publication.Version):
+		problems = append(problems, fmt.Sprintf("version %q is not a semantic version such as 1.2.3", publication.Version))
+	}
+
+	if publication.License == "" {
+		problems = append(problems, "license is missing, use an SPDX identifier such as MIT")
+	}
+
+	if publication.Main == "" {
+		problems = append(problems, "main is missing")
+	} else if _, err := os.Stat(filepath.Join(projectPath, filepath.FromSlash(publication.Main))); err != nil {
+		problems = append(problems, fmt.Sprintf("main file %s does not exist", publication.Main))
+	}
+
+	switch {
+	case publication.GithubURL == "":
+		problem := `repository is missing, set it to the URL packages are cloned from`
+		if origin := originURL(projectPath); origin != "" {
+			problem += fmt.Sprintf(`, e.g. "repository": %q`, origin)
+		}
+		problems = append(problems, problem)
+	case !isRemoteURL(publication.GithubURL):
+		problems = append(problems, fmt.Sprintf("repository %s is not a remote git URL", publication.GithubURL))
+	}
+
+	// devDependencies are only needed to work on the package, so they are not published
+	dependencies, _ := config["dependencies"].(map[string]interface{})
+	if len(dependencies) > 0 {
+		publication.Dependencies = map[string]string{}
+	}
+	for _, name := range utils.SortedKeys(dependencies) {
+		constraint, ok := dependencies[name].(string)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("dependency %s has no version range", name))
+			continue
+		}
+		if spec, ok := utils.ParseSourceSpecifier(constraint); ok {
+			if spec.IsLocal() {
+				problems = append(problems, fmt.Sprintf("dependency %s points at the local directory %s, which installs elsewhere cannot use", name, spec.Path))
+				continue
+			}
+		} else if _, err := utils.ParseConstraint(constraint); err != nil {
+			problems = append(problems, fmt.Sprintf("dependency %s: %v", name, err))
+			continue
+		}
+		publication.Dependencies[name] = constraint
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s.json is not ready to publish:\n  - %s", configs.JSONName, strings.Join(problems, "\n  - "))
+	}
+	return publication, nil
+}
+
+// repositoryURL reads "repository", given as a URL or as {"url": "..."}.
+func repositoryURL(value interface{}) string {
+	switch repository := value.(type) {
+	case string:
+		return strings.TrimSpace(repository)
+	case map[string]interface{}:
+		url, _ := repository["url"].(string)
+		return strings.TrimSpace(url)
+	}
+	return ""
+}
+
+// isRemoteURL reports whether url is a git URL other machines can clone.
+func isRemoteURL(url string) bool {
+	if spec, ok := utils.ParseSourceSpecifier(url); !ok || spec.IsLocal() {
+		return false
+	}
+	endpoint, err := transport.NewEndpoint(url)
+	return err == nil && endpoint.Protocol != "file" && endpoint.Host != ""
+}
+
+// originURL returns the URL of the project's origin remote, or "".
+func originURL(projectPath string) string {
+	repo, err := git.PlainOpenWithOptions(projectPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return ""
+	}
+	origin, err := repo.Remote("origin")
+	if err != nil || len(origin.Config().URLs) == 0 {
+		return ""
+	}
+	return origin.Config().URLs[0]
+}
+
+// checkPublishedTag finds the tag of the version in the project's repository
+// and makes sure the repository URL serves the same tag, recording the tag
+// and its commit in the publication.
+func checkPublishedTag(projectPath string, publication *registry.Publication) error {
+	repo, err := git.PlainOpenWithOptions(projectPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return fmt.Errorf("failed to open the git repository of the project: %v", err)
+	}
+
+	var tag *plumbing.Reference
+	for _, name := range []string{"v" + publication.Version, publication.Version} {
+		if tag, err = repo.Tag(name); err == nil {
+			break
+		}
+	}
+	if tag == nil {
+		return fmt.Errorf("version %s is not tagged, run git tag v%s and git push origin v%s",
+			publication.Version, publication.Version, publication.Version)
+	}
+	tagName := tag.Name().Short()
+	commit, err := repo.ResolveRevision(plumbing.Revision(tag.Name().String()))
+	if err != nil {
+		return fmt.Errorf("failed to resolve tag %s: %v", tagName, err)
+	}
+	publication.Ref = tagName
+	publication.Commit = commit.String()
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{publication.GithubURL},
+	})
+	auth, err := utils.GitAuth(publication.GithubURL)
+	if err != nil {
+		return err
+	}
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		refs, err = nil, nil
+	}
+	if errors.Is(err, transport.ErrRepositoryNotFound) {
+		return fmt.Errorf("repository %s does not exist, push the project to it first", publication.GithubURL)
+	} else if err != nil {
+		return fmt.Errorf("failed to list the tags of %s: %v", publication.GithubURL, utils.GitAuthError(publication.GithubURL, err))
+	}
+
+	for _, ref := range refs {
+		if ref.Name() != tag.Name() {
+			continue
+		}
+		if ref.Hash() != tag.Hash() {
+			return fmt.Errorf("tag %s in %s differs from the local one, push the tag again with git push --force origin %s",
+				tagName, publication.GithubURL, tagName)
+		}
+		return nil
+	}
+	return fmt.Errorf("tag %s has not been pushed to %s, run git push origin %s", tagName, publication.GithubURL, tagName)
+}
+
+func init() {
+	publishCmd.Flags().BoolVar(&publishDryRun, "dry-run", false, "Check the package and print the request instead of publishing")
+	rootCmd.AddCommand(publishCmd)
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"nep/configs"
+	"nep/registry"
 	"nep/utils"
 	"os"
 
@@ -12,10 +14,30 @@ import (
 type Scripts map[string]string
 
 var (
-	path    string
-	scripts Scripts
+	path             string
+	registryLocation string
+	refreshCache     bool
+	scripts          Scripts
 )
 
+// currentProject changes to --path and returns the project found there, or ""
+// outside a project, for commands that also work without one.
+func currentProject() (string, error) {
+	if err := utils.ChangeDirectory(path); err != nil {
+		return "", err
+	}
+	projectPath, err := utils.FindProjectDir()
+	if err != nil {
+		return "", nil
+	}
+	return projectPath, nil
+}
+
+// registryOptions returns the registry settings given on the command line.
+func registryOptions() registry.Options {
+	return registry.Options{Registry: registryLocation, Refresh: refreshCache}
+}
+
 func loadScripts() error {
 	projectPath := utils.Prepare(true, path)
 
@@ -48,7 +70,7 @@ func loadScripts() error {
 
 var rootCmd = &cobra.Command{
 	Use:     "nep [script]",
-	Version: "0.1.0",
+	Version: configs.Version,
 	Short:   "Nebula Pack - A package manager for Lua and LÖVE2D projects",
 	Long: `Nebula Pack (nep) is a specialized package manager designed to simplify 
 the process of installing and managing libraries for Lua and LÖVE2D projects.
@@ -100,4 +122,6 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&path, "path", "p", "", "Set project path")
+	rootCmd.PersistentFlags().StringVar(&registryLocation, "registry", "", "Use this registry instead of the configured ones (URL, git+<template> or directory)")
+	rootCmd.PersistentFlags().BoolVar(&refreshCache, "refresh", false, "Ignore cached registry metadata and ask the registry again")
 }
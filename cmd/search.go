@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"nep/registry"
+	"nep/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var interactiveSearch bool
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the registry for packages",
+	Long: `Search the configured registries for packages whose name or description
+matches the query. With --interactive the results are shown in a list that can
+be filtered by typing /, and the package picked with enter is installed into
+the current project.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		query := strings.Join(args, " ")
+
+		// Searching works outside a project too, with the user's registries
+		projectPath, err := currentProject()
+		if err != nil {
+			exitWithError(err)
+		}
+		// Installing the pick needs a project, and search should not create one
+		interactive := interactiveSearch && utils.IsTerminal()
+		if interactive && projectPath == "" {
+			exitWithError(fmt.Errorf("not a nep project, run nep init before installing from search"))
+		}
+
+		reg, err := registry.Load(projectPath, registryOptions())
+		if err != nil {
+			exitWithError(err)
+		}
+
+		results, err := reg.Search(query)
+		if errors.Is(err, registry.ErrSearchUnsupported) {
+			exitWithError(fmt.Errorf("the configured registries do not support searching"))
+		} else if err != nil {
+			exitWithError(err)
+		}
+
+		if len(results) == 0 {
+			fmt.Printf("No packages found for %q\n", query)
+			return
+		}
+
+		// The interactive list needs a terminal, so pipes get the table instead
+		if !interactive {
+			headers := []string{"Package", "Version", "Description", "Lua", "Rockspec"}
+			rows := [][]string{}
+			for _, result := range results {
+				rows = append(rows, []string{result.Key, result.Version, result.Description, yesNo(result.IsLua), yesNo(result.HasRockspec)})
+			}
+			if err := utils.DisplayTable(headers, rows); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		items := make([]utils.Item, len(results))
+		for i, result := range results {
+			items[i] = utils.Item{TitleText: result.Key, Desc: searchResultDetail(result)}
+		}
+		picked, err := utils.FilterFromList(fmt.Sprintf("Results for %q (enter installs)", query), items, 5)
+		if errors.Is(err, utils.ErrPromptCancelled) {
+			return
+		} else if err != nil {
+			exitWithError(err)
+		}
+
+		installPackages(projectPath, []string{picked})
+	},
+}
+
+// searchResultDetail is the line shown under a package in the interactive list.
+func searchResultDetail(result registry.SearchResult) string {
+	parts := []string{result.Version}
+	if result.IsLua {
+		parts = append(parts, "lua")
+	}
+	if result.HasRockspec {
+		parts = append(parts, "rockspec")
+	}
+	if result.Description != "" {
+		parts = append(parts, result.Description)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func init() {
+	searchCmd.Flags().BoolVarP(&interactiveSearch, "interactive", "i", false, "Pick a result to install from an interactive list")
+	rootCmd.AddCommand(searchCmd)
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"nep/configs"
+)
+
+// transaction stages the packages of an install run next to nebpack/ and only
+// moves them into place, together with the config and lockfile writes, once
+// every package is ready. Until then the project is left untouched, and a
+// failure during the final step puts everything back as it was. A Ctrl-C that
+// arrives during the final step waits for it to finish.
+type transaction struct {
+	mu          sync.Mutex
+	projectPath string
+	folderPath  string
+	root        string
+	stagePath   string
+	backupPath  string
+	// files holds the config and lockfile contents from before the commit; nil for missing files
+	files map[string][]byte
+	// moved lists the packages moved into nebpack/, and backedUp those whose previous install was moved aside
+	moved    []string
+	backedUp []string
+	finished bool
+	// committed is set once commit applied the install
+	committed bool
+	// beforeExit runs before an interrupted install exits, to give the terminal back
+	beforeExit func()
+}
+
+func newTransaction(projectPath, folderPath string) (*transaction, error) {
+	root := filepath.Join(projectPath, configs.CacheFolderName)
+
+	// Anything left here belongs to a run that was killed before it could clean up
+	if err := os.RemoveAll(root); err != nil {
+		return nil, fmt.Errorf("failed to clear %s: %v", root, err)
+	}
+
+	tx := &transaction{
+		projectPath: projectPath,
+		folderPath:  folderPath,
+		root:        root,
+		stagePath:   filepath.Join(root, "stage"),
+		backupPath:  filepath.Join(root, "backup"),
+		files:       map[string][]byte{},
+	}
+	if err := os.MkdirAll(tx.stagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	return tx, nil
+}
+
+// rollbackOnInterrupt rolls the transaction back and exits when the user presses
+// Ctrl-C. The returned function stops watching for the signal.
+func (tx *transaction) rollbackOnInterrupt() func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			if tx.beforeExit != nil {
+				tx.beforeExit()
+			}
+			if tx.rollback() {
+				fmt.Println("\nInterrupted, no changes were made")
+			} else {
+				fmt.Println("\nInterrupted after the install was applied, the changes were kept")
+			}
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// commit moves the staged packages into nebpack/ and runs write, which updates
+// the config and lockfile. Any failure restores the previous state.
+func (tx *transaction) commit(packages []string, write func() error) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.finished {
+		return errors.New("install was already rolled back")
+	}
+
+	for _, file := range []string{configs.JSONName, configs.LockFileName} {
+		filePath := filepath.Join(tx.projectPath, file+".json")
+		data, err := os.ReadFile(filePath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %v", filePath, err)
+		}
+		tx.files[filePath] = data
+	}
+
+	for _, name := range packages {
+		if err := tx.move(name); err != nil {
+			tx.undo()
+			return err
+		}
+	}
+
+	if err := write(); err != nil {
+		tx.undo()
+		return err
+	}
+
+	tx.finished, tx.committed = true, true
+	os.RemoveAll(tx.root)
+	return nil
+}
+
+// move replaces the installed copy of a package with the staged one.
+func (tx *transaction) move(name string) error {
+	staged := filepath.Join(tx.stagePath, name)
+	target := filepath.Join(tx.folderPath, name)
+
+	if _, err := os.Stat(target); err == nil {
+		backup := filepath.Join(tx.backupPath, name)
+		if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+			return fmt.Errorf("failed to back up %s: %v", name, err)
+		}
+		if err := os.Rename(target, backup); err != nil {
+			return fmt.Errorf("failed to back up %s: %v", name, err)
+		}
+		tx.backedUp = append(tx.backedUp, name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to install %s: %v", name, err)
+	}
+	if err := os.Rename(staged, target); err != nil {
+		return fmt.Errorf("failed to install %s: %v", name, err)
+	}
+	tx.moved = append(tx.moved, name)
+	return nil
+}
+
+// undo reverses everything commit did so far.
+func (tx *transaction) undo() {
+	for i := len(tx.moved) - 1; i >= 0; i-- {
+		os.RemoveAll(filepath.Join(tx.folderPath, tx.moved[i]))
+	}
+	for _, name := range tx.backedUp {
+		if err := os.Rename(filepath.Join(tx.backupPath, name), filepath.Join(tx.folderPath, name)); err != nil {
+			fmt.Printf("Error restoring %s: %v\n", name, err)
+		}
+	}
+
+	for filePath, data := range tx.files {
+		var err error
+		if data == nil {
+			err = os.Remove(filePath)
+		} else {
+			err = os.WriteFile(filePath, data, 0644)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Error restoring %s: %v\n", filePath, err)
+		}
+	}
+
+	tx.moved, tx.backedUp = nil, nil
+}
+
+// rollback abandons the transaction and removes the staged packages. It waits
+// for a commit in progress and reports false when that commit already applied
+// the install, which is then kept.
+func (tx *transaction) rollback() bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.finished {
+		return !tx.committed
+	}
+	tx.undo()
+	tx.finished = true
+	os.RemoveAll(tx.root)
+	return true
+}
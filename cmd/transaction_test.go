@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nep/configs"
+)
+
+func writeFile(t *testing.T, filePath, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile returns the content of a file, or "<missing>" when it does not exist.
+func readFile(t *testing.T, filePath string) string {
+	t.Helper()
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return "<missing>"
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestTransactionCommit(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed map[string]string
+		staged    map[string]string
+		packages  []string
+		writeErr  error
+		// want is the content of each package's init.lua after the commit
+		want       map[string]string
+		wantConfig string
+		wantErr    string
+	}{
+		{
+			name:       "new packages",
+			staged:     map[string]string{"foo": "foo 2", "studio/bar": "bar 1"},
+			packages:   []string{"foo", "studio/bar"},
+			want:       map[string]string{"foo": "foo 2", "studio/bar": "bar 1"},
+			wantConfig: "new config",
+		},
+		{
+			name:       "replaces installed packages",
+			installed:  map[string]string{"foo": "foo 1", "baz": "baz 1"},
+			staged:     map[string]string{"foo": "foo 2"},
+			packages:   []string{"foo"},
+			want:       map[string]string{"foo": "foo 2", "baz": "baz 1"},
+			wantConfig: "new config",
+		},
+		{
+			name:       "write failure restores everything",
+			installed:  map[string]string{"foo": "foo 1"},
+			staged:     map[string]string{"foo": "foo 2", "bar": "bar 1"},
+			packages:   []string{"foo", "bar"},
+			writeErr:   errors.New("disk full"),
+			want:       map[string]string{"foo": "foo 1", "bar": "<missing>"},
+			wantConfig: "old config",
+			wantErr:    "disk full",
+		},
+		{
+			name:       "missing staged package restores everything",
+			installed:  map[string]string{"foo": "foo 1"},
+			staged:     map[string]string{"foo": "foo 2"},
+			packages:   []string{"foo", "bar"},
+			want:       map[string]string{"foo": "foo 1", "bar": "<missing>"},
+			wantConfig: "old config",
+			wantErr:    "failed to install bar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projectPath := t.TempDir()
+			folderPath := filepath.Join(projectPath, configs.FolderName)
+			configPath := filepath.Join(projectPath, configs.JSONName+".json")
+			lockPath := filepath.Join(projectPath, configs.LockFileName+".json")
+			writeFile(t, configPath, "old config")
+			for name, content := range tt.installed {
+				writeFile(t, filepath.Join(folderPath, name, "init.lua"), content)
+			}
+
+			tx, err := newTransaction(projectPath, folderPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range tt.staged {
+				writeFile(t, filepath.Join(tx.stagePath, name, "init.lua"), content)
+			}
+
+			err = tx.commit(tt.packages, func() error {
+				writeFile(t, configPath, "new config")
+				writeFile(t, lockPath, "new lock")
+				return tt.writeErr
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("commit: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("commit error = %v, want %q", err, tt.wantErr)
+			}
+
+			for name, want := range tt.want {
+				if got := readFile(t, filepath.Join(folderPath, name, "init.lua")); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if got := readFile(t, configPath); got != tt.wantConfig {
+				t.Errorf("config = %q, want %q", got, tt.wantConfig)
+			}
+			// The lockfile did not exist before, so a failed commit removes it again
+			wantLock := "new lock"
+			if tt.wantErr != "" {
+				wantLock = "<missing>"
+			}
+			if got := readFile(t, lockPath); got != wantLock {
+				t.Errorf("lockfile = %q, want %q", got, wantLock)
+			}
+			if tt.wantErr == "" {
+				if _, err := os.Stat(tx.root); !os.IsNotExist(err) {
+					t.Errorf("%s was not removed after the commit", tx.root)
+				}
+			}
+		})
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	projectPath := t.TempDir()
+	folderPath := filepath.Join(projectPath, configs.FolderName)
+	writeFile(t, filepath.Join(folderPath, "foo", "init.lua"), "foo 1")
+
+	tx, err := newTransaction(projectPath, folderPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(tx.stagePath, "foo", "init.lua"), "foo 2")
+
+	tx.rollback()
+	if _, err := os.Stat(tx.root); !os.IsNotExist(err) {
+		t.Errorf("%s was not removed by the rollback", tx.root)
+	}
+	if got := readFile(t, filepath.Join(folderPath, "foo", "init.lua")); got != "foo 1" {
+		t.Errorf("foo = %q, want the installed copy kept", got)
+	}
+
+	// A rolled back transaction cannot be committed any more
+	if err := tx.commit([]string{"foo"}, func() error { return nil }); err == nil {
+		t.Error("commit after rollback succeeded, want an error")
+	}
+}
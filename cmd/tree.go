@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nep/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	treeDepth   int
+	treeJSON    bool
+	treeDot     bool
+	treeMermaid bool
+)
+
+// dependencyGraph is the installed dependency graph: the dependencies of the
+// project and the packages of the lockfile with the dependencies they declare.
+type dependencyGraph struct {
+	Name    string
+	Version string
+	Roots   map[string]string
+	Dev     map[string]bool
+	// Packages are the lockfile entries, keyed by name
+	Packages map[string]utils.LockedPackage
+	// devOnly holds the packages that are only reachable through devDependencies
+	devOnly map[string]bool
+}
+
+// treeNode is a package in the tree printed by nep tree, as printed by --json.
+type treeNode struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	// Range is the constraint the parent places on the package
+	Range string `json:"range,omitempty"`
+	Dev   bool   `json:"dev,omitempty"`
+	// Deduped packages are expanded where they first appear in the tree
+	Deduped bool `json:"deduped,omitempty"`
+	// Conflict is set when the installed version does not satisfy Range
+	Conflict     bool        `json:"conflict,omitempty"`
+	Missing      bool        `json:"missing,omitempty"`
+	Dependencies []*treeNode `json:"dependencies,omitempty"`
+}
+
+var treeCmd = &cobra.Command{
+	Use:   "tree",
+	Short: "Show the resolved dependency graph",
+	Long: `Print every installed package under the dependency that pulled it in, as
+recorded in nebula-lock.json. A package that appears more than once is only
+expanded the first time and marked deduped after that. Branches only reachable
+through devDependencies are marked dev, and packages whose installed version
+does not satisfy the range of a dependent are marked as conflicts.
+
+The graph can be exported with --json, or with --dot (Graphviz) and --mermaid
+for documentation.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		projectPath := utils.Prepare(false, path)
+
+		graph, err := loadDependencyGraph(projectPath)
+		if err != nil {
+			exitWithError(err)
+		}
+		root := graph.tree(treeDepth)
+
+		switch {
+		case treeJSON:
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(root); err != nil {
+				exitWithError(err)
+			}
+		case treeDot:
+			fmt.Print(treeDotGraph(root))
+		case treeMermaid:
+			fmt.Print(treeMermaidGraph(root))
+		default:
+			fmt.Print(utils.RenderTree(root.render(false)))
+		}
+	},
+}
+
+// loadDependencyGraph reads the dependency graph from the config and the lockfile.
+func loadDependencyGraph(projectPath string) (*dependencyGraph, error) {
+	if !utils.LockfileExists(projectPath) {
+		return nil, fmt.Errorf("no lockfile found, run nep install first")
+	}
+	lock, err := utils.ReadLockfile(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	roots, dev, err := readDependencies(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &dependencyGraph{
+		Name:     filepath.Base(projectPath),
+		Roots:    roots,
+		Dev:      dev,
+		Packages: lock.Packages,
+		devOnly:  map[string]bool{},
+	}
+	if results, err := utils.ReadConfig(projectPath, [][]string{{}}); err == nil {
+		config, _ := results[0].(map[string]interface{})
+		if name, _ := config["name"].(string); name != "" {
+			graph.Name = name
+		}
+		graph.Version, _ = config["version"].(string)
+	}
+
+	// Whatever the dependencies reach is needed at runtime, the rest only by devDependencies
+	runtime := runtimePackages(roots, dev, graph.Packages)
+	for name := range roots {
+		graph.devOnly[name] = !runtime[name]
+	}
+	for name := range graph.Packages {
+		graph.devOnly[name] = !runtime[name]
+	}
+
+	return graph, nil
+}
+
+// dependencies returns the dependencies of a package, or of the project for "".
+func (g *dependencyGraph) dependencies(name string) map[string]string {
+	if name == "" {
+		return g.Roots
+	}
+	return g.Packages[name].Dependencies
+}
+
+// satisfies reports whether the installed copy of a package meets constraint.
+func (g *dependencyGraph) satisfies(name, constraint string) bool {
+	locked, ok := g.Packages[name]
+	if !ok {
+		return false
+	}
+	if utils.IsSourceSpecifier(constraint) {
+		return locked.Specifier == constraint
+	}
+	return utils.Satisfies(locked.Version, constraint)
+}
+
+// tree lays the graph out as a tree, going at most maxDepth levels deep
+// when maxDepth is positive.
+func (g *dependencyGraph) tree(maxDepth int) *treeNode {
+	root := &treeNode{Name: g.Name, Version: strings.TrimPrefix(g.Version, "v")}
+
+	expanded := map[string]bool{}
+	var expand func(parent *treeNode, dependencies map[string]string, depth int)
+	expand = func(parent *treeNode, dependencies map[string]string, depth int) {
+		for _, name := range utils.SortedKeys(dependencies) {
+			node := &treeNode{Name: name, Range: dependencies[name], Dev: g.devOnly[name]}
+			parent.Dependencies = append(parent.Dependencies, node)
+
+			locked, ok := g.Packages[name]
+			if !ok {
+				node.Missing = true
+				continue
+			}
+			node.Version = strings.TrimPrefix(locked.Version, "v")
+			node.Conflict = !g.satisfies(name, node.Range)
+
+			if len(locked.Dependencies) == 0 || (maxDepth > 0 && depth >= maxDepth) {
+				continue
+			}
+			// Repeating the subtree of a package, or following a cycle, adds nothing
+			if expanded[name] {
+				node.Deduped = true
+				continue
+			}
+			expanded[name] = true
+			expand(node, locked.Dependencies, depth+1)
+		}
+	}
+	expand(root, g.Roots, 1)
+	return root
+}
+
+func (n *treeNode) title() string {
+	if n.Version == "" {
+		return n.Name
+	}
+	return n.Name + "@" + n.Version
+}
+
+// render converts the node for utils.RenderTree. Dev branches are only
+// marked where they start, below a node that is not dev-only itself.
+func (n *treeNode) render(parentDev bool) utils.TreeNode {
+	var notes []string
+	if n.Dev && !parentDev {
+		notes = append(notes, "dev")
+	}
+	if n.Missing {
+		notes = append(notes, "missing, wants "+utils.DescribeConstraint(n.Range))
+	} else if n.Conflict {
+		notes = append(notes, "conflict, wants "+utils.DescribeConstraint(n.Range))
+	}
+	if n.Deduped {
+		notes = append(notes, "deduped")
+	}
+
+	label := n.title()
+	if len(notes) > 0 {
+		label += " (" + strings.Join(notes, ", ") + ")"
+	}
+	node := utils.TreeNode{Label: label}
+	for _, child := range n.Dependencies {
+		node.Children = append(node.Children, child.render(n.Dev))
+	}
+	return node
+}
+
+// treeEdge is a dependency between two nodes of an exported graph.
+type treeEdge struct {
+	from, to string
+	node     *treeNode
+}
+
+// graphOf lists the nodes of the tree, each package once, and the edges
+// between them. Nodes are numbered n0 (the project), n1 and so on.
+func graphOf(root *treeNode) (nodes []*treeNode, ids []string, edges []treeEdge) {
+	seen := map[string]string{}
+	nodes, ids = []*treeNode{root}, []string{"n0"}
+	var walk func(parentID string, node *treeNode)
+	walk = func(parentID string, node *treeNode) {
+		for _, child := range node.Dependencies {
+			id, ok := seen[child.Name]
+			if !ok {
+				id = fmt.Sprintf("n%d", len(nodes))
+				seen[child.Name] = id
+				nodes, ids = append(nodes, child), append(ids, id)
+			}
+			edges = append(edges, treeEdge{from: parentID, to: id, node: child})
+			walk(id, child)
+		}
+	}
+	walk("n0", root)
+	return nodes, ids, edges
+}
+
+// treeDotGraph exports the tree as a Graphviz digraph.
+func treeDotGraph(root *treeNode) string {
+	nodes, ids, edges := graphOf(root)
+
+	var sb strings.Builder
+	sb.WriteString("digraph dependencies {\n")
+	sb.WriteString("  node [shape=box];\n")
+	for i, node := range nodes {
+		id := ids[i]
+		attributes := []string{fmt.Sprintf("label=%q", node.title())}
+		switch {
+		case i == 0:
+			attributes = append(attributes, "style=bold")
+		case node.Missing:
+			attributes = append(attributes, "color=red", "style=dashed")
+		case node.Dev:
+			attributes = append(attributes, "style=dashed")
+		}
+		fmt.Fprintf(&sb, "  %s [%s];\n", id, strings.Join(attributes, ", "))
+	}
+	for _, edge := range edges {
+		attributes := []string{fmt.Sprintf("label=%q", edge.node.Range)}
+		if edge.node.Conflict || edge.node.Missing {
+			attributes = append(attributes, "color=red")
+		}
+		fmt.Fprintf(&sb, "  %s -> %s [%s];\n", edge.from, edge.to, strings.Join(attributes, ", "))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// treeMermaidGraph exports the tree as a Mermaid flowchart.
+func treeMermaidGraph(root *treeNode) string {
+	nodes, ids, edges := graphOf(root)
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+	}
+
+	var sb strings.Builder
+	sb.WriteString("graph TD\n")
+	var dev, missing []string
+	for i, node := range nodes {
+		id := ids[i]
+		fmt.Fprintf(&sb, "  %s[%s]\n", id, quote(node.title()))
+		switch {
+		case i == 0:
+		case node.Missing:
+			missing = append(missing, id)
+		case node.Dev:
+			dev = append(dev, id)
+		}
+	}
+	var conflicts []string
+	for i, edge := range edges {
+		if edge.node.Range == "" {
+			fmt.Fprintf(&sb, "  %s --> %s\n", edge.from, edge.to)
+		} else {
+			fmt.Fprintf(&sb, "  %s -->|%s| %s\n", edge.from, quote(edge.node.Range), edge.to)
+		}
+		if edge.node.Conflict || edge.node.Missing {
+			conflicts = append(conflicts, fmt.Sprint(i))
+		}
+	}
+
+	if len(dev) > 0 {
+		sb.WriteString("  classDef dev stroke-dasharray: 5 5\n")
+		fmt.Fprintf(&sb, "  class %s dev\n", strings.Join(dev, ","))
+	}
+	if len(missing) > 0 {
+		sb.WriteString("  classDef missing stroke:#d33,stroke-dasharray: 5 5\n")
+		fmt.Fprintf(&sb, "  class %s missing\n", strings.Join(missing, ","))
+	}
+	if len(conflicts) > 0 {
+		fmt.Fprintf(&sb, "  linkStyle %s stroke:#d33\n", strings.Join(conflicts, ","))
+	}
+	return sb.String()
+}
+
+func init() {
+	treeCmd.Flags().IntVar(&treeDepth, "depth", 0, "Show this many levels of dependencies, 1 being the direct ones (0 shows all)")
+	treeCmd.Flags().BoolVar(&treeJSON, "json", false, "Print the tree as JSON")
+	treeCmd.Flags().BoolVar(&treeDot, "dot", false, "Print the graph in Graphviz DOT format")
+	treeCmd.Flags().BoolVar(&treeMermaid, "mermaid", false, "Print the graph as a Mermaid flowchart")
+	treeCmd.MarkFlagsMutuallyExclusive("json", "dot", "mermaid")
+	rootCmd.AddCommand(treeCmd)
+}
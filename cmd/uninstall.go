@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"nep/configs"
+	"nep/registry"
 	"nep/utils"
 	"os"
 	"path/filepath"
@@ -35,6 +37,14 @@ func runUninstall(cmd *cobra.Command, args []string) {
 		exitWithError(fmt.Errorf("error updating config: %v", err))
 	}
 
+	lockUpdates := map[string]*utils.LockedPackage{}
+	for _, update := range updates {
+		lockUpdates[update.Path[len(update.Path)-1]] = nil
+	}
+	if err := utils.UpdateLockfile(projectPath, lockUpdates); err != nil {
+		exitWithError(fmt.Errorf("error updating lock file: %v", err))
+	}
+
 	fmt.Println("Packages uninstalled successfully.")
 }
 
@@ -50,45 +60,70 @@ func getUpdates(projectPath string, args []string) ([]utils.UpdatePath, error) {
 		if err := os.RemoveAll(pkgPath); err != nil {
 			fmt.Printf("Warning: Error removing package %s: %v\n", pkg, err)
 		}
-		updates = append(updates, utils.UpdatePath{Path: []string{"dependencies", pkg}, Value: configs.RemoveMarker})
+		updates = append(updates, removeDependency(pkg)...)
 	}
 
 	return updates, nil
 }
 
+// removeDependency returns the updates removing a package from both dependency sets.
+func removeDependency(pkg string) []utils.UpdatePath {
+	return []utils.UpdatePath{
+		{Path: []string{"dependencies", pkg}, Value: configs.RemoveMarker},
+		{Path: []string{"devDependencies", pkg}, Value: configs.RemoveMarker},
+	}
+}
+
 func getAllUpdates(projectPath string) ([]utils.UpdatePath, error) {
-	keys := [][]string{{"dependencies"}}
-	results, err := utils.ReadConfig(projectPath, keys)
+	depMap, _, err := readDependencies(projectPath)
 	if err != nil {
 		return nil, fmt.Errorf("error reading config: %v", err)
 	}
 
-	if len(results) == 0 {
+	if len(depMap) == 0 {
 		return nil, fmt.Errorf("no dependencies found")
 	}
 
-	depMap, ok := results[0].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("dependencies are not in the expected format")
-	}
-
 	var updates []utils.UpdatePath
 	for pkg := range depMap {
 		pkgPath := filepath.Join(projectPath, configs.FolderName, pkg)
 		if err := os.RemoveAll(pkgPath); err != nil {
 			fmt.Printf("Warning: Error removing package %s: %v\n", pkg, err)
 		}
-		updates = append(updates, utils.UpdatePath{Path: []string{"dependencies", pkg}, Value: configs.RemoveMarker})
+		updates = append(updates, removeDependency(pkg)...)
 	}
 
 	return updates, nil
 }
 
 func exitWithError(err error) {
-	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	printError(err)
 	os.Exit(1)
 }
 
+// printError prints err to stderr, followed by a hint when there is one.
+func printError(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if hint := errorHint(err); hint != "" {
+		fmt.Fprintln(os.Stderr, hint)
+	}
+}
+
+// errorHint suggests what to do about the registry errors a command can run into.
+func errorHint(err error) string {
+	switch {
+	case errors.Is(err, registry.ErrRegistryUnavailable):
+		return "Check your connection or the registry settings"
+	case errors.Is(err, registry.ErrPackageNotFound):
+		return "Check the package name, or pass --registry to look it up elsewhere"
+	case errors.Is(err, registry.ErrVersionNotFound):
+		return "Check the requested version against the versions the package has published"
+	case errors.Is(err, registry.ErrVersionExists):
+		return "Published versions cannot change, bump the version in nebula-config.json and tag it"
+	}
+	return ""
+}
+
 func init() {
 	uninstallCmd.Flags().StringVarP(&path, "path", "p", "", "Set project path")
 	rootCmd.AddCommand(uninstallCmd)
@@ -3,11 +3,11 @@ package cmd
 import (
 	"fmt"
 	"nep/configs"
+	"nep/registry"
 	"nep/utils"
 	"os"
 	"path/filepath"
-
-	"strings"
+	"runtime"
 
 	"github.com/spf13/cobra"
 )
@@ -20,76 +20,63 @@ var updateCmd = &cobra.Command{
 
 		projectPath := utils.Prepare(false, path)
 
-		cachePath := filepath.Join(projectPath, configs.CacheFolderName)
 		packagePath := filepath.Join(projectPath, configs.FolderName)
 
-		if len(args) > 0 && args[0] == configs.All {
-			// Update all packages
-			updateAllPackages(projectPath, cachePath, packagePath)
-		} else {
-			// Update specific packages
-			for _, pkg := range args {
-				installPackage(pkg, projectPath, cachePath)
-			}
-			updateSpecificPackages(args, cachePath, packagePath)
-		}
-
-		if err := os.Remove(cachePath); err != nil {
-			fmt.Printf("Error removing cache folder: %v\n", err)
-		}
-
-	},
-}
-
-func updateAllPackages(projectPath string, cachePath string, packagePath string) {
-	keys := [][]string{
-		{"dependencies"},
-	}
-	packs := []string{}
-	results, err := utils.ReadConfig(projectPath, keys)
-	if err != nil {
-		fmt.Printf("Error reading config: %v\n", err)
-		os.Exit(1)
-	}
-	if len(results) > 0 {
-		depMap, ok := results[0].(map[string]interface{})
-		if !ok {
-			fmt.Println("Error: dependencies are not in the expected format")
+		lock, err := utils.ReadLockfile(projectPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		for pkg := range depMap {
-			packs = append(packs, pkg)
-			installPackage(pkg, projectPath, cachePath)
+		roots, dev, err := readDependencies(projectPath)
+		if err != nil {
+			exitWithError(err)
 		}
-		updateSpecificPackages(packs, cachePath, packagePath)
-	}
 
-}
+		// Cached metadata could hide versions published since it was stored
+		options := registryOptions()
+		options.Refresh = true
+		reg, err := registry.Load(projectPath, options)
+		if err != nil {
+			exitWithError(err)
+		}
 
-func updateSpecificPackages(packages []string, cachePath, packagePath string) {
-	for _, pkg := range packages {
-		pkg = strings.Split(pkg, "::")[0]
-		sourcePath := filepath.Join(cachePath, pkg)
-		destPath := filepath.Join(packagePath, pkg)
+		// New versions are staged and only moved over once they are all ready
+		inst := newInstaller(projectPath, packagePath, lock, reg)
+		inst.dev = dev
+		inst.jobs = jobs
 
-		if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
-			fmt.Printf("Package %s not found in cache\n", pkg)
-			continue
+		if len(args) > 0 && args[0] == configs.All {
+			// Update all packages within the ranges declared in the config
+			inst.refresh[configs.All] = true
+		} else {
+			// Update specific packages
+			for _, pkg := range args {
+				name, constraint := utils.SplitPackageArg(pkg)
+				inst.refresh[name] = true
+				if constraint != "" || roots[name] == "" {
+					roots[name] = constraint
+				}
+			}
 		}
 
-		if err := os.RemoveAll(destPath); err != nil {
-			fmt.Printf("Error removing package %s: %v\n", pkg, err)
-			continue
+		if dryRun {
+			if err := inst.resolve(roots); err != nil {
+				exitWithError(err)
+			}
+			inst.printPlan()
+			return
 		}
 
-		if err := os.Rename(sourcePath, destPath); err != nil {
-			fmt.Printf("Error updating package %s: %v\n", pkg, err)
+		if err := inst.run(roots); err != nil {
+			exitWithError(err)
 		}
-	}
+	},
 }
 
 func init() {
+	updateCmd.Flags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "Number of packages to update at the same time")
+	updateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Resolve dependencies and print the plan without updating")
 	updateCmd.Flags().StringVarP(&path, "path", "p", "", "Set project path")
 	rootCmd.AddCommand(updateCmd)
 }
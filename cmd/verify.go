@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"nep/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check installed packages against the lockfile",
+	Long: `Re-hash every package in nebpack/ and compare it with the integrity hash
+recorded in nebula-lock.json. Modified, missing and extra files are listed,
+and the command exits with a non-zero status if anything differs. A package
+without a recorded integrity hash cannot be checked and also fails. Dev-only
+packages that nep install --production left out are skipped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		projectPath := utils.Prepare(false, path)
+		folderPath := utils.GetFolder(projectPath)
+
+		if !utils.LockfileExists(projectPath) {
+			exitWithError(fmt.Errorf("no lockfile found, run nep install first"))
+		}
+		lock, err := utils.ReadLockfile(projectPath)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		var failed, missing, skipped int
+		for _, name := range utils.SortedKeys(lock.Packages) {
+			locked := lock.Packages[name]
+			packageDir := filepath.Join(folderPath, name)
+			if _, err := os.Stat(packageDir); os.IsNotExist(err) {
+				if locked.Dev {
+					fmt.Printf("%s: not installed, only needed by devDependencies\n", name)
+					skipped++
+				} else {
+					fmt.Printf("%s: missing\n", name)
+					missing++
+				}
+				continue
+			}
+			if !verifyPackage(name, locked, packageDir) {
+				failed++
+			}
+		}
+
+		checked := len(lock.Packages) - skipped
+		if failed+missing > 0 {
+			fmt.Printf("%d of %d packages failed verification\n", failed+missing, checked)
+			if failed > 0 {
+				fmt.Println("Remove the modified packages from nebpack/ and run nep install to restore them")
+			}
+			if missing > 0 {
+				fmt.Println("Run nep install to restore the missing packages")
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("All %d packages verified\n", checked)
+	},
+}
+
+// verifyPackage checks one installed package and reports the result, returning false on a mismatch.
+func verifyPackage(name string, locked utils.LockedPackage, packageDir string) bool {
+	if _, err := os.Stat(packageDir); err != nil {
+		fmt.Printf("%s: %v\n", name, err)
+		return false
+	}
+	if locked.Integrity == "" {
+		fmt.Printf("%s: no integrity recorded, run nep install to add it\n", name)
+		return false
+	}
+
+	actual, err := utils.FileHashes(packageDir)
+	if err != nil {
+		fmt.Printf("%s: %v\n", name, err)
+		return false
+	}
+	if utils.HashFiles(actual) == locked.Integrity {
+		fmt.Printf("%s: ok\n", name)
+		return true
+	}
+
+	fmt.Printf("%s: modified\n", name)
+
+	// The store keeps a pristine copy of the commit, which tells which files changed
+	if locked.Commit == "" {
+		fmt.Println("    installed from a local directory, there is no copy to compare files against")
+		return false
+	}
+	treeDir, ok := utils.StoreTreeDir(locked.GithubURL, locked.Commit)
+	if !ok {
+		fmt.Println("    the package store has no copy of this version to compare files against")
+		return false
+	}
+	expected, err := utils.FileHashes(treeDir)
+	if err != nil || utils.HashFiles(expected) != locked.Integrity {
+		fmt.Println("    the package store copy does not match the lockfile either")
+		return false
+	}
+
+	diff := utils.DiffFiles(expected, actual)
+	for _, file := range diff.Modified {
+		fmt.Printf("    modified: %s\n", file)
+	}
+	for _, file := range diff.Missing {
+		fmt.Printf("    missing:  %s\n", file)
+	}
+	for _, file := range diff.Extra {
+		fmt.Printf("    extra:    %s\n", file)
+	}
+	return false
+}
+
+func init() {
+	verifyCmd.Flags().StringVarP(&path, "path", "p", "", "Set project path")
+	rootCmd.AddCommand(verifyCmd)
+}
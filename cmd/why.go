@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nep/configs"
+	"nep/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// whyMaxPaths limits the paths printed for packages required from many places.
+const whyMaxPaths = 50
+
+// dependencyStep is an edge of a path through the dependency graph: the
+// package reached and the constraint its dependent places on it.
+type dependencyStep struct {
+	name       string
+	constraint string
+}
+
+var whyCmd = &cobra.Command{
+	Use:   "why <package>",
+	Short: "Explain why a package is installed",
+	Long: `Print every path from the project to a package through the dependency graph
+recorded in nebula-lock.json, with the version range required at each step.
+A package in nebpack/ that nothing depends on is reported as an orphan.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		projectPath := utils.Prepare(false, path)
+
+		graph, err := loadDependencyGraph(projectPath)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		paths, truncated := graph.pathsTo(name, whyMaxPaths)
+		if len(paths) == 0 {
+			explainOrphan(projectPath, graph, name)
+			return
+		}
+
+		locked, installed := graph.Packages[name]
+		title := name
+		if installed {
+			title += "@" + strings.TrimPrefix(locked.Version, "v")
+		}
+		kind := "a transitive dependency"
+		if _, ok := graph.Roots[name]; ok {
+			kind = "a direct dependency"
+			if graph.Dev[name] {
+				kind = "a direct dev dependency"
+			}
+		} else if graph.devOnly[name] {
+			kind = "a transitive dependency of devDependencies"
+		}
+
+		count := fmt.Sprintf("%d paths", len(paths))
+		if len(paths) == 1 {
+			count = "1 path"
+		} else if truncated {
+			count = fmt.Sprintf("more than %d paths, the first %d are shown", whyMaxPaths, whyMaxPaths)
+		}
+		if installed {
+			fmt.Printf("%s is %s, required through %s:\n", title, kind, count)
+		} else {
+			fmt.Printf("%s is %s but is not installed, required through %s:\n", title, kind, count)
+		}
+
+		root := graph.Name
+		if graph.Version != "" {
+			root += "@" + strings.TrimPrefix(graph.Version, "v")
+		}
+		for _, steps := range paths {
+			parts := []string{root}
+			for i, step := range steps {
+				parts = append(parts, graph.describeStep(step, i == 0 && graph.Dev[step.name]))
+			}
+			fmt.Println("  " + strings.Join(parts, " → "))
+		}
+
+		if !installed {
+			fmt.Println("Run nep install to install it")
+		}
+	},
+}
+
+// pathsTo returns up to limit paths from the project to target, reporting
+// whether there were more. Packages are not repeated within a path, so
+// dependency cycles are not followed.
+func (g *dependencyGraph) pathsTo(target string, limit int) ([][]dependencyStep, bool) {
+	// Only packages that lead to the target are worth walking through
+	dependents := map[string][]string{}
+	for _, name := range append(utils.SortedKeys(g.Packages), "") {
+		for dep := range g.dependencies(name) {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+	leads := map[string]bool{target: true}
+	queue := []string{target}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, dependent := range dependents[name] {
+			if !leads[dependent] {
+				leads[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	if !leads[""] {
+		return nil, false
+	}
+
+	var (
+		paths     [][]dependencyStep
+		truncated bool
+		steps     []dependencyStep
+	)
+	onPath := map[string]bool{}
+	var walk func(name string)
+	walk = func(name string) {
+		dependencies := g.dependencies(name)
+		for _, dep := range utils.SortedKeys(dependencies) {
+			if !leads[dep] || onPath[dep] || truncated {
+				continue
+			}
+			steps = append(steps, dependencyStep{name: dep, constraint: dependencies[dep]})
+			if dep == target {
+				if len(paths) == limit {
+					truncated = true
+				} else {
+					paths = append(paths, append([]dependencyStep(nil), steps...))
+				}
+			} else {
+				onPath[dep] = true
+				walk(dep)
+				onPath[dep] = false
+			}
+			steps = steps[:len(steps)-1]
+		}
+	}
+	walk("")
+	return paths, truncated
+}
+
+// describeStep prints a step as the package reached and the range required
+// of it. dev marks the first step of a path through devDependencies.
+func (g *dependencyGraph) describeStep(step dependencyStep, dev bool) string {
+	notes := []string{utils.DescribeConstraint(step.constraint)}
+	if dev {
+		notes = append(notes, "dev")
+	}
+
+	title := step.name
+	if locked, ok := g.Packages[step.name]; !ok {
+		notes = append(notes, "not installed")
+	} else {
+		title += "@" + strings.TrimPrefix(locked.Version, "v")
+		if !g.satisfies(step.name, step.constraint) {
+			notes = append(notes, "not satisfied")
+		}
+	}
+	return fmt.Sprintf("%s (%s)", title, strings.Join(notes, ", "))
+}
+
+// explainOrphan reports a package that no path leads to.
+func explainOrphan(projectPath string, graph *dependencyGraph, name string) {
+	packageDir := filepath.Join(projectPath, configs.FolderName, name)
+	_, dirErr := os.Stat(packageDir)
+	locked, inLock := graph.Packages[name]
+	if dirErr != nil && !inLock {
+		exitWithError(fmt.Errorf("%s is not installed and nothing depends on it", name))
+	}
+
+	title := name
+	if saved, err := utils.ReadSavedResponse(packageDir); err == nil {
+		title += "@" + strings.TrimPrefix(saved.Data.Version, "v")
+	} else if inLock {
+		title += "@" + strings.TrimPrefix(locked.Version, "v")
+	}
+
+	var where []string
+	if dirErr == nil {
+		where = append(where, filepath.ToSlash(filepath.Join(configs.FolderName, name)))
+	}
+	if inLock {
+		where = append(where, configs.LockFileName+".json")
+	}
+	fmt.Printf("%s is an orphan: it is in %s, but neither the project nor any installed package depends on it\n",
+		title, strings.Join(where, " and "))
+	fmt.Printf("Run nep uninstall %s to remove it\n", name)
+}
+
+func init() {
+	rootCmd.AddCommand(whyCmd)
+}
@@ -1,5 +1,16 @@
 package configs
 
 const (
+	// Version is the nep release, reported by --version and in the registry User-Agent.
+	Version = "0.1.0"
+	// APIBaseURL is the registry used when none is configured.
 	APIBaseURL = "http://localhost:2321"
+	// RegistryEnv overrides the default registry for a single invocation.
+	RegistryEnv = "NEP_REGISTRY"
+	// TokenEnv holds a registry token for CI jobs that cannot run nep login. It is
+	// only sent when a single registry other than the default is configured, scoped
+	// or not.
+	TokenEnv = "NEP_TOKEN"
+	// UserConfigName is the per-user config file kept in the nep config directory.
+	UserConfigName = "config"
 )
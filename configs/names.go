@@ -6,10 +6,11 @@ import (
 
 const (
 	JSONName         string = "nebula-config"
+	LockFileName     string = "nebula-lock"
 	FolderName       string = "nebpack"
 	CacheFolderName  string = "nebpack-cache"
 	DefaultName      string = "Nebula-Pack-Project"
-	ResponseFileName string = "nebula-config"
+	ResponseFileName string = "nebula-package"
 	RemoveMarker     string = "__REMOVE__"
 	All              string = "*"
 	// add version seperator
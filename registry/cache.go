@@ -0,0 +1,209 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"nep/utils"
+)
+
+const (
+	// defaultTTL applies when a response says nothing about how long it stays valid
+	defaultTTL = 5 * time.Minute
+	minTTL     = time.Minute
+	maxTTL     = 24 * time.Hour
+)
+
+// Cache keeps registry responses on disk, one file per package and version:
+//
+//	<cache>/<registry hash>/<name>/<version>.json   metadata of a version
+//	<cache>/<registry hash>/<name>/latest.json      metadata of the latest version
+//	<cache>/<registry hash>/<name>/versions.json    published versions
+//
+// A response is reused without a request until it expires, after which it is
+// revalidated with If-None-Match / If-Modified-Since so that an unchanged
+// package costs a 304 instead of a full response.
+type Cache struct {
+	Dir string
+	// Refresh ignores cached responses; the new responses are still stored
+	Refresh bool
+}
+
+type cacheEntry struct {
+	URL      string    `json:"url"`
+	StoredAt time.Time `json:"stored_at"`
+	Expires  time.Time `json:"expires"`
+	ETag     string    `json:"etag,omitempty"`
+	// LastModified is an HTTP date, from the Last-Modified header or the temporal_semantics of the response
+	LastModified string          `json:"last_modified,omitempty"`
+	Body         json.RawMessage `json:"body"`
+}
+
+// CacheDir returns the metadata cache directory, ~/.cache/nep/metadata unless NEP_METADATA_CACHE is set.
+func CacheDir() (string, error) {
+	if dir := os.Getenv("NEP_METADATA_CACHE"); dir != "" {
+		return dir, nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache directory: %v", err)
+	}
+	return filepath.Join(cacheDir, "nep", "metadata"), nil
+}
+
+// OpenCache returns the metadata cache, or nil when there is nowhere to keep it.
+func OpenCache(refresh bool) *Cache {
+	dir, err := CacheDir()
+	if err != nil {
+		return nil
+	}
+	return &Cache{Dir: dir, Refresh: refresh}
+}
+
+func (c *Cache) registryDir(baseURL string) string {
+	sum := sha256.Sum256([]byte(strings.TrimRight(baseURL, "/")))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])[:16])
+}
+
+func (c *Cache) path(baseURL, name, key string) string {
+	return filepath.Join(c.registryDir(baseURL), url.PathEscape(name), url.PathEscape(key)+".json")
+}
+
+// Clear removes every cached response of a registry.
+func (c *Cache) Clear(baseURL string) error {
+	if c == nil {
+		return nil
+	}
+	return os.RemoveAll(c.registryDir(baseURL))
+}
+
+// load returns the cached response for a package, or nil.
+func (c *Cache) load(baseURL, name, key string) *cacheEntry {
+	if c == nil || c.Refresh {
+		return nil
+	}
+	data, err := os.ReadFile(c.path(baseURL, name, key))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// store saves a response. Failures only cost a request next time, so they are ignored.
+func (c *Cache) store(baseURL, name, key string, entry *cacheEntry) {
+	if c == nil {
+		return
+	}
+	entryPath := c.path(baseURL, name, key)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(entryPath), 0755); err != nil {
+		return
+	}
+
+	// Parallel installs may store the same entry, so replace it atomically
+	tmp, err := os.CreateTemp(filepath.Dir(entryPath), ".entry-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	tmp.Close()
+	if err != nil || os.Rename(tmp.Name(), entryPath) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (e *cacheEntry) fresh() bool {
+	return time.Now().Before(e.Expires)
+}
+
+// revalidation returns the conditional request headers for a stale entry.
+func (e *cacheEntry) revalidation() http.Header {
+	header := http.Header{}
+	if e.ETag != "" {
+		header.Set("If-None-Match", e.ETag)
+	}
+	if e.LastModified != "" {
+		header.Set("If-Modified-Since", e.LastModified)
+	}
+	return header
+}
+
+// newCacheEntry describes a response for the cache. It returns nil when the
+// registry asked for the response not to be stored.
+func newCacheEntry(apiUrl string, header http.Header, body []byte) *cacheEntry {
+	entry := &cacheEntry{
+		URL:      apiUrl,
+		StoredAt: time.Now(),
+		ETag:     header.Get("ETag"),
+		Body:     body,
+	}
+
+	entry.LastModified = header.Get("Last-Modified")
+	if entry.LastModified == "" {
+		var response utils.Response
+		if json.Unmarshal(body, &response) == nil {
+			if t, err := time.Parse(time.RFC3339, response.TemporalSemantics.LatestGetRequest); err == nil {
+				entry.LastModified = t.UTC().Format(http.TimeFormat)
+			}
+		}
+	}
+
+	ttl, ok := entry.ttl(header)
+	if !ok {
+		return nil
+	}
+	entry.Expires = entry.StoredAt.Add(ttl)
+	return entry
+}
+
+// ttl works out how long a response stays fresh, following HTTP caching:
+// Cache-Control and Expires are used when present. Otherwise the response
+// stays fresh for a tenth of the time since the registry last fetched the
+// package, as told by Last-Modified or temporal_semantics.latest-get-request,
+// so packages that have not changed in a long time are checked less often.
+func (e *cacheEntry) ttl(header http.Header) (time.Duration, bool) {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store":
+			return 0, false
+		case directive == "no-cache":
+			return 0, true
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				return time.Duration(max(seconds, 0)) * time.Second, true
+			}
+		}
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0, true
+		}
+		return max(time.Until(t), 0), true
+	}
+
+	if lastModified, err := http.ParseTime(e.LastModified); err == nil {
+		age := e.StoredAt.Sub(lastModified)
+		return min(max(age/10, minTTL), maxTTL), true
+	}
+	return defaultTTL, true
+}
@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheEntryTTL(t *testing.T) {
+	now := time.Now()
+	httpDate := func(d time.Duration) string {
+		return now.Add(d).UTC().Format(http.TimeFormat)
+	}
+
+	tests := []struct {
+		name         string
+		header       http.Header
+		lastModified string
+		want         time.Duration
+		ok           bool
+	}{
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=60"}}, "", time.Minute, true},
+		{"max-age wins over Expires", http.Header{"Cache-Control": {"max-age=30"}, "Expires": {httpDate(time.Hour)}}, "", 30 * time.Second, true},
+		{"no-cache", http.Header{"Cache-Control": {"no-cache"}}, "", 0, true},
+		{"no-store", http.Header{"Cache-Control": {"no-store"}}, "", 0, false},
+		{"Expires", http.Header{"Expires": {httpDate(time.Hour)}}, "", time.Hour, true},
+		{"Expires in the past", http.Header{"Expires": {httpDate(-time.Hour)}}, "", 0, true},
+		{"invalid Expires", http.Header{"Expires": {"0"}}, "", 0, true},
+		{"tenth of the age", http.Header{}, httpDate(-100 * time.Minute), 10 * time.Minute, true},
+		{"at least a minute", http.Header{}, httpDate(-2 * time.Minute), minTTL, true},
+		{"at most a day", http.Header{}, httpDate(-100 * 24 * time.Hour), maxTTL, true},
+		{"no caching headers", http.Header{}, "", defaultTTL, true},
+	}
+	for _, tt := range tests {
+		entry := &cacheEntry{StoredAt: now, LastModified: tt.lastModified}
+		got, ok := entry.ttl(tt.header)
+		// HTTP dates have a resolution of a second
+		if ok != tt.ok || got > tt.want+time.Second || got < tt.want-time.Second {
+			t.Errorf("%s: ttl = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHTTPCacheRevalidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		refresh      bool
+		// wantRequests counts the requests made by two Versions calls
+		wantRequests int32
+		// wantRevalidated reports whether the second request was conditional
+		wantRevalidated bool
+	}{
+		{"fresh entry is reused", "max-age=60", false, 1, false},
+		{"stale entry is revalidated", "no-cache", false, 2, true},
+		{"refresh skips the cache", "max-age=60", true, 2, false},
+		{"no-store is not cached", "no-store", false, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			var revalidated atomic.Bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.Header().Set("Cache-Control", tt.cacheControl)
+				w.Header().Set("ETag", `"v1"`)
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					revalidated.Store(true)
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Write([]byte(`{"key": "foo", "versions": ["1.0.0", "1.1.0"]}`))
+			}))
+			defer srv.Close()
+
+			reg := NewHTTP(srv.URL)
+			reg.Cache = &Cache{Dir: t.TempDir(), Refresh: tt.refresh}
+			for i := 0; i < 2; i++ {
+				versions, err := reg.Versions("foo")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(versions) != 2 {
+					t.Fatalf("Versions = %v, want the two published versions", versions)
+				}
+			}
+
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("%d requests, want %d", got, tt.wantRequests)
+			}
+			if got := revalidated.Load(); got != tt.wantRevalidated {
+				t.Errorf("revalidated = %v, want %v", got, tt.wantRevalidated)
+			}
+		})
+	}
+}
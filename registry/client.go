@@ -0,0 +1,156 @@
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"nep/configs"
+)
+
+// Client is the HTTP client used to talk to registries. Requests time out,
+// and connection errors, 429 and 5xx responses are retried with exponential
+// backoff, honouring Retry-After when the registry sends it.
+type Client struct {
+	HTTP      *http.Client
+	UserAgent string
+	// Token is sent as Bearer authorization when set
+	Token      string
+	MaxRetries int
+	// BaseDelay is the wait before the first retry; it doubles on every attempt up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// NewClient creates a client with nep's default timeouts and retry policy.
+func NewClient() *Client {
+	return &Client{
+		HTTP: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 20 * time.Second,
+				MaxIdleConnsPerHost:   8,
+			},
+		},
+		UserAgent:  fmt.Sprintf("nep/%s (%s/%s)", configs.Version, runtime.GOOS, runtime.GOARCH),
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+	}
+}
+
+// RawResponse is a fully read registry response.
+type RawResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Get requests url with the extra header, retrying transient failures. An
+// error is only returned when the registry could not be reached or kept
+// failing, in which case it wraps ErrRegistryUnavailable; other statuses
+// are left to the caller.
+func (c *Client) Get(url string, header http.Header) (*RawResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		resp, err := c.do(req)
+
+		var retryAfter time.Duration
+		switch {
+		case err != nil:
+			lastErr = err
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("%s returned %s", url, http.StatusText(resp.StatusCode))
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		default:
+			return resp, nil
+		}
+
+		if attempt >= c.MaxRetries {
+			return nil, fmt.Errorf("%w: %v", ErrRegistryUnavailable, lastErr)
+		}
+		time.Sleep(c.backoff(attempt, retryAfter))
+	}
+}
+
+// Post sends body to url once. Requests that change the registry are not
+// retried, as a request that timed out may still have been applied.
+func (c *Client) Post(url string, body []byte) (*RawResponse, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRegistryUnavailable, err)
+	}
+	return resp, nil
+}
+
+func (c *Client) do(req *http.Request) (*RawResponse, error) {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %v", req.URL, err)
+	}
+	return &RawResponse{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+// backoff returns the wait before retrying after the given attempt.
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, c.MaxDelay)
+	}
+	delay := c.BaseDelay << attempt
+	// Jitter keeps parallel installs from retrying in lockstep
+	delay += time.Duration(rand.Int63n(int64(c.BaseDelay) + 1))
+	return min(delay, c.MaxDelay)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"0", 0},
+		{"-5", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), -time.Hour},
+	}
+	for _, tt := range tests {
+		got := parseRetryAfter(tt.value)
+		// Dates are relative to now, so allow for the time the test takes
+		if got > tt.want || got < tt.want-2*time.Second {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got <= 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %v, want about a minute", date, got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := &Client{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		attempt    int
+		retryAfter time.Duration
+		min, max   time.Duration
+	}{
+		{0, 0, 100 * time.Millisecond, 200 * time.Millisecond},
+		{1, 0, 200 * time.Millisecond, 300 * time.Millisecond},
+		{2, 0, 400 * time.Millisecond, 500 * time.Millisecond},
+		{5, 0, time.Second, time.Second},
+		{0, 300 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond},
+		{0, time.Minute, time.Second, time.Second},
+	}
+	for _, tt := range tests {
+		got := c.backoff(tt.attempt, tt.retryAfter)
+		if got < tt.min || got > tt.max {
+			t.Errorf("backoff(%d, %v) = %v, want between %v and %v", tt.attempt, tt.retryAfter, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestClientGetRetries(t *testing.T) {
+	tests := []struct {
+		name string
+		// statuses are returned in order, the last one for every further request
+		statuses     []int
+		wantStatus   int
+		wantRequests int32
+		unavailable  bool
+	}{
+		{"success", []int{200}, 200, 1, false},
+		{"recovers from 503", []int{503, 503, 200}, 200, 3, false},
+		{"recovers from 429", []int{429, 200}, 200, 2, false},
+		{"not found is not retried", []int{404}, 404, 1, false},
+		{"keeps failing", []int{500}, 0, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(requests.Add(1))
+				status := tt.statuses[min(n, len(tt.statuses))-1]
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "1")
+				}
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			c := NewClient()
+			c.MaxRetries = 2
+			c.BaseDelay = time.Millisecond
+			c.MaxDelay = 10 * time.Millisecond
+
+			resp, err := c.Get(srv.URL, nil)
+			if tt.unavailable {
+				if !errors.Is(err, ErrRegistryUnavailable) {
+					t.Errorf("Get error = %v, want ErrRegistryUnavailable", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("%d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestClientGetUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	c := NewClient()
+	c.MaxRetries = 1
+	c.BaseDelay = time.Millisecond
+	if _, err := c.Get(url, nil); !errors.Is(err, ErrRegistryUnavailable) {
+		t.Errorf("Get error = %v, want ErrRegistryUnavailable", err)
+	}
+}
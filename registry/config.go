@@ -0,0 +1,189 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nep/configs"
+	"nep/utils"
+)
+
+// Source is a registry entry of the "registries" list in the project or user config:
+//
+//	"registries": [
+//	  {"url": "https://registry.studio.internal", "scopes": ["studio/*"], "priority": 10},
+//	  {"url": "https://registry.example.com"}
+//	]
+type Source struct {
+	URL      string   `json:"url"`
+	Scopes   []string `json:"scopes,omitempty"`
+	Priority int      `json:"priority,omitempty"`
+	Fallback bool     `json:"fallback,omitempty"`
+}
+
+// Settings are the registry keys of a config file. "registry" is a shorthand
+// for a single unscoped entry.
+type Settings struct {
+	Registry   string   `json:"registry,omitempty"`
+	Registries []Source `json:"registries,omitempty"`
+}
+
+func (s Settings) sources() []Source {
+	var sources []Source
+	if s.Registry != "" {
+		sources = append(sources, Source{URL: s.Registry})
+	}
+	return append(sources, s.Registries...)
+}
+
+// Options are the command line settings that affect the registry.
+type Options struct {
+	// Registry overrides the configured unscoped registries, usually the --registry flag
+	Registry string
+	// Refresh bypasses the metadata cache, usually the --refresh flag
+	Refresh bool
+}
+
+// Load builds the registry for a project. Registries are taken from, in order
+// of precedence:
+//
+//  1. options.Registry
+//  2. the NEP_REGISTRY environment variable
+//  3. the project's nebula-config.json
+//  4. the user config, ~/.config/nep/config.json
+//
+// The flag and the environment variable hold a comma separated list of
+// locations that replaces the configured unscoped registries; scoped
+// routes from the config files still apply. The built-in default is used
+// when no unscoped registry is configured at all.
+//
+// NEP_TOKEN does not name a registry, so it is only sent when a single HTTP
+// registry other than the default is configured and has no stored token.
+func Load(projectDir string, options Options) (Registry, error) {
+	sources, err := Sources(projectDir, options.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := OpenCache(options.Refresh)
+	credentials, err := utils.ReadCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	registries := make([]Registry, len(sources))
+	tokenTargets := map[string][]*HTTP{}
+	for i, source := range sources {
+		reg, err := open(source.URL, cache, credentials)
+		if err != nil {
+			return nil, err
+		}
+		registries[i] = reg
+		if h, ok := reg.(*HTTP); ok && h.BaseURL != configs.APIBaseURL {
+			tokenTargets[h.BaseURL] = append(tokenTargets[h.BaseURL], h)
+		}
+	}
+	if len(tokenTargets) == 1 {
+		for _, targets := range tokenTargets {
+			for _, h := range targets {
+				if h.Client.Token == "" {
+					h.Client.Token = os.Getenv(configs.TokenEnv)
+				}
+			}
+		}
+	}
+
+	if len(registries) == 1 {
+		return registries[0], nil
+	}
+	routes := make([]Route, len(sources))
+	for i, source := range sources {
+		routes[i] = Route{Registry: registries[i], Scopes: source.Scopes, Priority: source.Priority, Fallback: source.Fallback}
+	}
+	return NewRouter(routes), nil
+}
+
+// Sources lists the registries configured for a project, in the order
+// described at Load, with override replacing the unscoped ones.
+func Sources(projectDir, override string) ([]Source, error) {
+	var sources []Source
+
+	if projectDir != "" {
+		var project Settings
+		if err := readSettings(filepath.Join(projectDir, configs.JSONName+".json"), &project); err != nil {
+			return nil, err
+		}
+		for _, source := range project.sources() {
+			source.URL = projectLocation(projectDir, source.URL)
+			sources = append(sources, source)
+		}
+	}
+
+	var user Settings
+	if err := utils.ReadUserConfig(&user); err != nil {
+		return nil, err
+	}
+	sources = append(sources, user.sources()...)
+
+	if override == "" {
+		override = os.Getenv(configs.RegistryEnv)
+	}
+	if override != "" {
+		scoped := sources[:0]
+		for _, source := range sources {
+			if len(source.Scopes) > 0 {
+				scoped = append(scoped, source)
+			}
+		}
+		sources = scoped
+		for _, location := range strings.Split(override, ",") {
+			if location = strings.TrimSpace(location); location != "" {
+				sources = append(sources, Source{URL: location})
+			}
+		}
+	}
+
+	hasDefault := false
+	for _, source := range sources {
+		hasDefault = hasDefault || len(source.Scopes) == 0
+	}
+	if !hasDefault {
+		sources = append(sources, Source{URL: configs.APIBaseURL})
+	}
+	return sources, nil
+}
+
+// readSettings reads the registry keys of a JSON config file, if it exists.
+func readSettings(configFilePath string, settings *Settings) error {
+	configFileBytes, err := os.ReadFile(configFilePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	if err := json.Unmarshal(configFileBytes, settings); err != nil {
+		return fmt.Errorf("failed to parse registries in %s: %v", configFilePath, err)
+	}
+	return nil
+}
+
+// projectLocation resolves directory registries relative to the project.
+func projectLocation(projectDir, location string) string {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "git+") {
+		return location
+	}
+
+	dir, hasScheme := strings.CutPrefix(location, "file:")
+	if filepath.IsAbs(dir) {
+		return location
+	}
+	dir = filepath.Join(projectDir, dir)
+	if hasScheme {
+		return "file:" + dir
+	}
+	return dir
+}
@@ -0,0 +1,149 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"nep/utils"
+)
+
+// Dir is a registry stored in a local directory:
+//
+//	<root>/<name>/<version>.json   metadata in the same format as the HTTP API
+//	<root>/<name>.git              bare repository, used when github_url is empty
+//
+// It lets teams run an internal registry from a shared folder and makes it
+// possible to exercise nep without the hosted service.
+type Dir struct {
+	Root string
+}
+
+// NewDir creates a registry backed by the directory at root.
+func NewDir(root string) (*Dir, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("registry directory %s does not exist", abs)
+	}
+	return &Dir{Root: abs}, nil
+}
+
+func (d *Dir) Name() string {
+	return d.Root
+}
+
+func (d *Dir) Versions(name string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(d.Root, filepath.FromSlash(name), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: %s in %s", ErrPackageNotFound, name, d.Root)
+	}
+
+	versions := make([]string, len(files))
+	for i, file := range files {
+		versions[i] = strings.TrimSuffix(filepath.Base(file), ".json")
+	}
+	return utils.SortVersions(versions), nil
+}
+
+func (d *Dir) metadataPath(name, version string) string {
+	return filepath.Join(d.Root, filepath.FromSlash(name), strings.TrimPrefix(version, "v")+".json")
+}
+
+func (d *Dir) Fetch(name, version string) (*utils.Response, error) {
+	if version == "" {
+		latest, err := Latest(d, name)
+		if err != nil {
+			return nil, err
+		}
+		version = latest
+	}
+
+	metadataPath := d.metadataPath(name, version)
+	metadata, err := os.ReadFile(metadataPath)
+	if os.IsNotExist(err) {
+		if _, versionsErr := d.Versions(name); versionsErr != nil {
+			return nil, versionsErr
+		}
+		return nil, fmt.Errorf("%w: %s@%s in %s", ErrVersionNotFound, name, version, d.Root)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var responseData utils.Response
+	if err := json.Unmarshal(metadata, &responseData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", metadataPath, err)
+	}
+
+	if responseData.Key == "" {
+		responseData.Key = name
+	}
+	if responseData.Data.Version == "" {
+		responseData.Data.Version = strings.TrimPrefix(version, "v")
+	}
+	if responseData.Data.GithubURL == "" {
+		responseData.Data.GithubURL = filepath.Join(d.Root, filepath.FromSlash(name)+".git")
+	}
+
+	return &responseData, nil
+}
+
+// Search matches the query against package names and descriptions.
+func (d *Dir) Search(query string) ([]SearchResult, error) {
+	query = strings.ToLower(query)
+
+	var names []string
+	err := filepath.Walk(d.Root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && strings.HasSuffix(path, ".git") {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".json") {
+			rel, err := filepath.Rel(d.Root, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	seen := map[string]bool{}
+	sort.Strings(names)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		responseData, err := d.Fetch(name, "")
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(name), query) && !strings.Contains(strings.ToLower(responseData.Data.Description), query) {
+			continue
+		}
+		results = append(results, SearchResult{
+			Key:         responseData.Key,
+			Version:     responseData.Data.Version,
+			Description: responseData.Data.Description,
+			IsLua:       responseData.Data.IsLua,
+			HasRockspec: responseData.Data.HasRockspec,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"nep/utils"
+)
+
+// Git resolves packages straight from git repositories: the versions of a
+// package are the semver tags of its repository. The repository URL is built
+// from a template in which {name} is replaced by the package name, for example
+// https://github.com/{name}.git for names such as kikito/inspect.lua.
+type Git struct {
+	Template string
+}
+
+// NewGit creates a registry that maps package names to repositories using template.
+func NewGit(template string) (*Git, error) {
+	if !strings.Contains(template, "{name}") {
+		return nil, fmt.Errorf("git registry template %q must contain {name}", template)
+	}
+	return &Git{Template: template}, nil
+}
+
+func (g *Git) Name() string {
+	return "git+" + g.Template
+}
+
+func (g *Git) url(name string) string {
+	return strings.ReplaceAll(g.Template, "{name}", name)
+}
+
+// tags lists the version tags of a package's repository, keyed by version.
+func (g *Git) tags(name string) (map[string]string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{g.url(name)},
+	})
+
+	auth, err := utils.GitAuth(g.url(name))
+	if err != nil {
+		return nil, err
+	}
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if errors.Is(err, transport.ErrRepositoryNotFound) {
+		return nil, fmt.Errorf("%w: %s has no repository at %s", ErrPackageNotFound, name, g.url(name))
+	}
+	if errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed) {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthorized, utils.GitAuthError(g.url(name), err))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w: %s", g.url(name), ErrRegistryUnavailable, err)
+	}
+
+	tags := map[string]string{}
+	for _, ref := range refs {
+		if !ref.Name().IsTag() {
+			continue
+		}
+		tag := ref.Name().Short()
+		if _, err := utils.ParseVersion(tag); err == nil {
+			tags[strings.TrimPrefix(tag, "v")] = tag
+		}
+	}
+	return tags, nil
+}
+
+func (g *Git) Versions(name string) ([]string, error) {
+	tags, err := g.tags(name)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(tags))
+	for version := range tags {
+		versions = append(versions, version)
+	}
+	return utils.SortVersions(versions), nil
+}
+
+func (g *Git) Fetch(name, version string) (*utils.Response, error) {
+	tags, err := g.tags(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == "" {
+		var versions []string
+		for v := range tags {
+			versions = append(versions, v)
+		}
+		sorted := utils.SortVersions(versions)
+		if len(sorted) == 0 {
+			return nil, fmt.Errorf("%w: %s has no version tags", ErrVersionNotFound, g.url(name))
+		}
+		version = sorted[len(sorted)-1]
+	}
+
+	version = strings.TrimPrefix(version, "v")
+	tag, ok := tags[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s@%s, %s has no matching tag", ErrVersionNotFound, name, version, g.url(name))
+	}
+
+	return &utils.Response{
+		Key: name,
+		Data: utils.Data{
+			GithubURL: g.url(name),
+			Version:   version,
+			Ref:       tag,
+		},
+	}, nil
+}
+
+func (g *Git) Search(query string) ([]SearchResult, error) {
+	return nil, ErrSearchUnsupported
+}
@@ -0,0 +1,190 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"nep/utils"
+)
+
+// HTTP is the registry API served by the Nebula Pack registry.
+type HTTP struct {
+	BaseURL string
+	Client  *Client
+	// Cache keeps responses between runs; nil disables caching
+	Cache *Cache
+}
+
+// NewHTTP creates a registry backed by the HTTP API at baseURL.
+func NewHTTP(baseURL string) *HTTP {
+	return &HTTP{BaseURL: strings.TrimRight(baseURL, "/"), Client: NewClient()}
+}
+
+// StatusError is an unexpected, non-retryable status returned by the registry.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	// Message is the error reported by the registry, if any
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("registry returned %d %s for %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func isStatus(err error, code int) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == code
+}
+
+func (h *HTTP) Name() string {
+	return h.BaseURL
+}
+
+// Fetch fetches data from the API for a given package.
+func (h *HTTP) Fetch(name, version string) (*utils.Response, error) {
+	apiUrl := fmt.Sprintf("%s/api/%s", h.BaseURL, name)
+	if version != "" {
+		apiUrl = fmt.Sprintf("%s/api/%s:v%s", h.BaseURL, name, strings.TrimPrefix(version, "v"))
+	}
+
+	key := "latest"
+	if version != "" {
+		key = strings.TrimPrefix(version, "v")
+	}
+
+	var responseData utils.Response
+	err := h.get(apiUrl, name, key, &responseData)
+	if isStatus(err, http.StatusNotFound) {
+		if version == "" {
+			return nil, fmt.Errorf("%w: %s in %s", ErrPackageNotFound, name, h.BaseURL)
+		}
+		// The API answers 404 for both, so ask whether the package exists at all
+		if _, versionsErr := h.Versions(name); errors.Is(versionsErr, ErrPackageNotFound) {
+			return nil, versionsErr
+		}
+		return nil, fmt.Errorf("%w: %s@%s in %s", ErrVersionNotFound, name, version, h.BaseURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &responseData, nil
+}
+
+// VersionsResponse represents the structure of the API response listing a package's versions.
+type VersionsResponse struct {
+	Key      string   `json:"key"`
+	Versions []string `json:"versions"`
+}
+
+// Versions fetches every published version of a package from the API.
+func (h *HTTP) Versions(name string) ([]string, error) {
+	var versionsData VersionsResponse
+	err := h.get(fmt.Sprintf("%s/api/%s/versions", h.BaseURL, name), name, "versions", &versionsData)
+	if isStatus(err, http.StatusNotFound) {
+		return nil, fmt.Errorf("%w: %s in %s", ErrPackageNotFound, name, h.BaseURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return versionsData.Versions, nil
+}
+
+// SearchResponse represents the structure of the API search response.
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+}
+
+// Search queries the API's search endpoint.
+func (h *HTTP) Search(query string) ([]SearchResult, error) {
+	var searchData SearchResponse
+	err := h.get(fmt.Sprintf("%s/api/search?q=%s", h.BaseURL, url.QueryEscape(query)), query, "", &searchData)
+	if isStatus(err, http.StatusNotFound) {
+		return nil, ErrSearchUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	return searchData.Results, nil
+}
+
+// get requests apiUrl and decodes the JSON body into target. Statuses other
+// than 2xx are returned as a *StatusError. Responses about a package are
+// cached under key, search results (an empty key) are not.
+func (h *HTTP) get(apiUrl, subject, key string, target interface{}) error {
+	var cached *cacheEntry
+	if key != "" {
+		cached = h.Cache.load(h.BaseURL, subject, key)
+	}
+	if cached != nil && cached.fresh() {
+		if err := json.Unmarshal(cached.Body, target); err == nil {
+			return nil
+		}
+		cached = nil
+	}
+
+	var header http.Header
+	if cached != nil {
+		header = cached.revalidation()
+	}
+	resp, err := h.Client.Get(apiUrl, header)
+	if err != nil {
+		return fmt.Errorf("failed to fetch data from API for %s: %w", subject, err)
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		// Keep the validators of the cached response unless the registry sent new ones
+		if resp.Header.Get("ETag") == "" && cached.ETag != "" {
+			resp.Header.Set("ETag", cached.ETag)
+		}
+		if resp.Header.Get("Last-Modified") == "" && cached.LastModified != "" {
+			resp.Header.Set("Last-Modified", cached.LastModified)
+		}
+		resp.StatusCode, resp.Body = http.StatusOK, cached.Body
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		if h.Client.Token == "" {
+			return fmt.Errorf("%w: %s requires a login, run nep login %s", ErrUnauthorized, h.BaseURL, h.BaseURL)
+		}
+		return fmt.Errorf("%w: %s rejected the token for %s (%d %s), run nep login %s to replace it",
+			ErrUnauthorized, h.BaseURL, subject, resp.StatusCode, http.StatusText(resp.StatusCode), h.BaseURL)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(apiUrl, resp)
+	}
+
+	if err := json.Unmarshal(resp.Body, target); err != nil {
+		return fmt.Errorf("unexpected response from the registry for %s, expected JSON: %s", subject, err)
+	}
+	if key != "" {
+		if entry := newCacheEntry(apiUrl, resp.Header, resp.Body); entry != nil {
+			h.Cache.store(h.BaseURL, subject, key, entry)
+		}
+	}
+	return nil
+}
+
+// responseError returns the *StatusError for an unexpected status.
+func responseError(apiUrl string, resp *RawResponse) error {
+	// Registries report errors as {"error": "..."} or {"message": "..."}
+	var body struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	json.Unmarshal(resp.Body, &body)
+	message := body.Error
+	if message == "" {
+		message = body.Message
+	}
+	return &StatusError{URL: apiUrl, StatusCode: resp.StatusCode, Message: message}
+}
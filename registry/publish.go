@@ -0,0 +1,150 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"nep/utils"
+)
+
+// ErrVersionExists is returned when publishing a version the registry already has.
+var ErrVersionExists = errors.New("version already published")
+
+// Publication is a package version submitted to a registry by nep publish.
+type Publication struct {
+	Key         string `json:"key"`
+	Version     string `json:"version"`
+	GithubURL   string `json:"github_url"`
+	Ref         string `json:"ref"`
+	Commit      string `json:"commit"`
+	Description string `json:"description,omitempty"`
+	Author      string `json:"author,omitempty"`
+	License     string `json:"license"`
+	Main        string `json:"main"`
+	// Dependencies are the runtime dependencies; devDependencies are not published
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// Publisher is implemented by registries that accept new packages.
+type Publisher interface {
+	// PublishRequest describes where Publish sends a package, such as
+	// "POST https://host/api/publish", and returns the exact body it sends.
+	PublishRequest(p *Publication) (string, []byte, error)
+	Publish(p *Publication) error
+}
+
+func (h *HTTP) publishURL() string {
+	return h.BaseURL + "/api/publish"
+}
+
+func (h *HTTP) PublishRequest(p *Publication) (string, []byte, error) {
+	body, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+	return "POST " + h.publishURL(), body, nil
+}
+
+// Publish submits the package to the API's publish endpoint.
+func (h *HTTP) Publish(p *Publication) error {
+	_, body, err := h.PublishRequest(p)
+	if err != nil {
+		return err
+	}
+	resp, err := h.Client.Post(h.publishURL(), body)
+	if err != nil {
+		return fmt.Errorf("failed to publish %s@%s: %w", p.Key, p.Version, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		if h.Client.Token == "" {
+			return fmt.Errorf("%w: publishing to %s requires a login, run nep login %s", ErrUnauthorized, h.BaseURL, h.BaseURL)
+		}
+		return fmt.Errorf("%w: %s rejected the token for publishing %s (%d %s), run nep login %s to replace it",
+			ErrUnauthorized, h.BaseURL, p.Key, resp.StatusCode, http.StatusText(resp.StatusCode), h.BaseURL)
+	case resp.StatusCode == http.StatusConflict:
+		return fmt.Errorf("%w: %s@%s is already in %s", ErrVersionExists, p.Key, p.Version, h.BaseURL)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return responseError(h.publishURL(), resp)
+	}
+	return nil
+}
+
+// PublishRequest for a directory registry is the metadata file it writes.
+func (d *Dir) PublishRequest(p *Publication) (string, []byte, error) {
+	body, err := json.MarshalIndent(utils.Response{
+		Key: p.Key,
+		Data: utils.Data{
+			GithubURL:    p.GithubURL,
+			Version:      p.Version,
+			Description:  p.Description,
+			Ref:          p.Ref,
+			Dependencies: p.Dependencies,
+		},
+	}, "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+	return "write " + d.metadataPath(p.Key, p.Version), body, nil
+}
+
+// Publish adds the version's metadata file to the directory.
+func (d *Dir) Publish(p *Publication) error {
+	_, body, err := d.PublishRequest(p)
+	if err != nil {
+		return err
+	}
+
+	metadataPath := d.metadataPath(p.Key, p.Version)
+	if err := os.MkdirAll(filepath.Dir(metadataPath), 0755); err != nil {
+		return fmt.Errorf("failed to publish %s@%s: %v", p.Key, p.Version, err)
+	}
+	// O_EXCL keeps two publishers of the same version from overwriting each other
+	f, err := os.OpenFile(metadataPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("%w: %s@%s is already in %s", ErrVersionExists, p.Key, p.Version, d.Root)
+	} else if err != nil {
+		return fmt.Errorf("failed to publish %s@%s: %v", p.Key, p.Version, err)
+	}
+	_, err = f.Write(append(body, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(metadataPath)
+		return fmt.Errorf("failed to publish %s@%s: %v", p.Key, p.Version, err)
+	}
+	return nil
+}
+
+// publisherFor returns the registry a package is published to: the first one
+// serving its name that accepts packages.
+func (r *Router) publisherFor(name string) (Publisher, error) {
+	for _, reg := range r.registriesFor(name) {
+		if publisher, ok := reg.(Publisher); ok {
+			return publisher, nil
+		}
+	}
+	return nil, fmt.Errorf("none of the registries serving %s accept published packages", name)
+}
+
+func (r *Router) PublishRequest(p *Publication) (string, []byte, error) {
+	publisher, err := r.publisherFor(p.Key)
+	if err != nil {
+		return "", nil, err
+	}
+	return publisher.PublishRequest(p)
+}
+
+func (r *Router) Publish(p *Publication) error {
+	publisher, err := r.publisherFor(p.Key)
+	if err != nil {
+		return err
+	}
+	return publisher.Publish(p)
+}
@@ -0,0 +1,118 @@
+// Package registry defines where nep looks up packages and provides the
+// registry backends: the hosted HTTP API, a local directory and plain git repositories.
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"nep/utils"
+)
+
+// ErrRegistryUnavailable is returned when the registry cannot be reached at all.
+var ErrRegistryUnavailable = errors.New("registry unavailable")
+
+// ErrPackageNotFound is returned when the registry does not know a package.
+var ErrPackageNotFound = errors.New("package not found")
+
+// ErrVersionNotFound is returned when a package exists but the requested version does not.
+var ErrVersionNotFound = errors.New("version not found")
+
+// ErrUnauthorized is returned when the registry requires credentials or rejects the ones sent.
+var ErrUnauthorized = errors.New("not authorized")
+
+// ErrSearchUnsupported is returned by registries that cannot search for packages.
+var ErrSearchUnsupported = errors.New("search is not supported by this registry")
+
+// Registry is a source of package metadata.
+type Registry interface {
+	// Name identifies the registry in messages, usually by its URL or path.
+	Name() string
+	// Fetch returns the metadata of a package version; an empty version means the latest release.
+	Fetch(name, version string) (*utils.Response, error)
+	// Versions returns every published version of a package.
+	Versions(name string) ([]string, error)
+	// Search returns the packages matching a free-text query.
+	Search(query string) ([]SearchResult, error)
+}
+
+// SearchResult is a single package returned by Search.
+type SearchResult struct {
+	Key         string `json:"key"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	IsLua       bool   `json:"isLua"`
+	HasRockspec bool   `json:"hasRockspec"`
+}
+
+// New creates a registry from a location:
+//
+//	http://host[:port], https://host   the registry HTTP API
+//	git+<url template>                 git repositories, e.g. git+https://github.com/{name}.git
+//	file:<dir> or an existing <dir>    a local directory registry
+func New(location string) (Registry, error) {
+	return open(location, nil, nil)
+}
+
+// open is New with the metadata cache and credentials used by HTTP registries.
+func open(location string, cache *Cache, credentials *utils.Credentials) (Registry, error) {
+	switch {
+	case strings.HasPrefix(location, "http://"), strings.HasPrefix(location, "https://"):
+		reg := NewHTTP(location)
+		reg.Cache = cache
+		reg.Client.Token = credentials.RegistryToken(reg.BaseURL)
+		return reg, nil
+	case strings.HasPrefix(location, "git+"):
+		return NewGit(strings.TrimPrefix(location, "git+"))
+	case strings.HasPrefix(location, "file:"):
+		return NewDir(strings.TrimPrefix(location, "file:"))
+	}
+
+	if info, err := os.Stat(location); err == nil && info.IsDir() {
+		return NewDir(location)
+	}
+	return nil, fmt.Errorf("unrecognized registry location %q", location)
+}
+
+// Resolve fetches the metadata for the highest version of a package matching constraint.
+// Exact versions are fetched directly; ranges are resolved against the published version list.
+func Resolve(reg Registry, name, constraint string) (*utils.Response, error) {
+	if constraint == "" {
+		return reg.Fetch(name, "")
+	}
+	if utils.IsExactVersion(constraint) {
+		return reg.Fetch(name, strings.TrimPrefix(constraint, "v"))
+	}
+
+	c, err := utils.ParseConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := reg.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+
+	version, ok := c.MaxSatisfying(versions)
+	if !ok {
+		return nil, fmt.Errorf("no version of %s matches %s (available: %s)", name, constraint, strings.Join(versions, ", "))
+	}
+
+	return reg.Fetch(name, strings.TrimPrefix(version, "v"))
+}
+
+// Latest returns the highest published version of a package.
+func Latest(reg Registry, name string) (string, error) {
+	versions, err := reg.Versions(name)
+	if err != nil {
+		return "", err
+	}
+	sorted := utils.SortVersions(versions)
+	if len(sorted) == 0 {
+		return "", fmt.Errorf("no versions of %s are published", name)
+	}
+	return sorted[len(sorted)-1], nil
+}
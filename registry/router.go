@@ -0,0 +1,183 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"nep/utils"
+)
+
+// Route sends the packages matching its scopes to a registry. A route without
+// scopes serves every package that no scoped route claims.
+type Route struct {
+	Registry Registry
+	// Scopes are package name patterns such as studio/* or an exact name.
+	Scopes []string
+	// Priority orders routes serving the same package, highest first.
+	Priority int
+	// Fallback lets packages of a scoped route fall through to the unscoped
+	// registries when this route does not have them.
+	Fallback bool
+}
+
+func (r Route) matches(name string) bool {
+	for _, scope := range r.Scopes {
+		if matchScope(scope, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchScope reports whether a package name matches a scope pattern.
+func matchScope(scope, name string) bool {
+	if scope == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(scope, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return scope == name
+}
+
+// Router is a Registry spreading packages over several registries.
+// For each package the matching registries are tried in order and the first
+// one that knows the package answers.
+type Router struct {
+	Routes []Route
+}
+
+// NewRouter creates a router, ordering routes by priority and keeping the
+// given order between routes of equal priority.
+func NewRouter(routes []Route) *Router {
+	sorted := append([]Route(nil), routes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	return &Router{Routes: sorted}
+}
+
+func (r *Router) Name() string {
+	names := make([]string, len(r.Routes))
+	for i, route := range r.Routes {
+		names[i] = route.Registry.Name()
+		if len(route.Scopes) > 0 {
+			names[i] += " (" + strings.Join(route.Scopes, ", ") + ")"
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// registriesFor returns the registries to ask for a package, in order.
+func (r *Router) registriesFor(name string) []Registry {
+	var registries []Registry
+	fallback := true
+	for _, route := range r.Routes {
+		if len(route.Scopes) > 0 && route.matches(name) {
+			registries = append(registries, route.Registry)
+			fallback = fallback && route.Fallback
+		}
+	}
+	if len(registries) > 0 && !fallback {
+		return registries
+	}
+
+	for _, route := range r.Routes {
+		if len(route.Scopes) == 0 {
+			registries = append(registries, route.Registry)
+		}
+	}
+	return registries
+}
+
+func (r *Router) Fetch(name, version string) (*utils.Response, error) {
+	var errs []error
+	for _, reg := range r.registriesFor(name) {
+		responseData, err := reg.Fetch(name, version)
+		if err == nil {
+			return responseData, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, routeError(name, errs)
+}
+
+func (r *Router) Versions(name string) ([]string, error) {
+	var errs []error
+	for _, reg := range r.registriesFor(name) {
+		versions, err := reg.Versions(name)
+		if err == nil && len(versions) > 0 {
+			return versions, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("%s has no versions of %s", reg.Name(), name)
+		}
+		errs = append(errs, err)
+	}
+	return nil, routeError(name, errs)
+}
+
+// Search queries every registry that supports searching and merges the
+// results, keeping the first result for each package.
+func (r *Router) Search(query string) ([]SearchResult, error) {
+	var results []SearchResult
+	var errs []error
+	seen := map[string]bool{}
+	searched := false
+	for _, route := range r.Routes {
+		found, err := route.Registry.Search(query)
+		if errors.Is(err, ErrSearchUnsupported) {
+			continue
+		}
+		searched = true
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, result := range found {
+			if !seen[result.Key] {
+				seen[result.Key] = true
+				results = append(results, result)
+			}
+		}
+	}
+
+	if !searched {
+		return nil, ErrSearchUnsupported
+	}
+	if len(results) == 0 && len(errs) > 0 {
+		return nil, routeError(query, errs)
+	}
+	return results, nil
+}
+
+// routeError combines the errors of every registry that was tried. The result
+// keeps a typed error only when every registry failed the same way, so that
+// for example ErrRegistryUnavailable means no registry could be reached.
+func routeError(subject string, errs []error) error {
+	switch len(errs) {
+	case 0:
+		return fmt.Errorf("no registry is configured for %s", subject)
+	case 1:
+		return errs[0]
+	}
+
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	message := strings.Join(messages, "\n  - ")
+
+	for _, sentinel := range []error{ErrRegistryUnavailable, ErrUnauthorized, ErrPackageNotFound, ErrVersionNotFound} {
+		shared := true
+		for _, err := range errs {
+			shared = shared && errors.Is(err, sentinel)
+		}
+		if shared {
+			return fmt.Errorf("%w for %s:\n  - %s", sentinel, subject, message)
+		}
+	}
+	return fmt.Errorf("no registry could provide %s:\n  - %s", subject, message)
+}
@@ -0,0 +1,432 @@
+// Package resolver picks a consistent set of package versions for a dependency graph.
+//
+// The solver works in the spirit of PubGrub: it decides one package at a time,
+// always choosing the most constrained package next and the highest version that
+// satisfies every constraint collected so far. When a choice leads to a dead end
+// it backtracks, and when no assignment exists it explains the conflict in terms
+// of the requirements that caused it.
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"nep/utils"
+)
+
+// maxSteps bounds the number of versions the solver may try before giving up.
+const maxSteps = 10000
+
+// Source provides the package information the solver needs.
+type Source interface {
+	// Versions returns every published version of a package.
+	Versions(name string) ([]string, error)
+	// Dependencies returns the dependencies declared by a specific version of a package.
+	Dependencies(name, version string) (map[string]string, error)
+}
+
+// Requirement is a constraint placed on a package by the project or by another package.
+type Requirement struct {
+	// Parent is the name of the requiring package, or empty for the project itself.
+	Parent        string
+	ParentVersion string
+	Constraint    string
+}
+
+func (r Requirement) describeParent() string {
+	if r.Parent == "" {
+		return "the project"
+	}
+	return r.Parent + "@" + r.ParentVersion
+}
+
+// Package is a single decision in a solution.
+type Package struct {
+	Name         string
+	Version      string
+	Dependencies map[string]string
+	RequiredBy   []Requirement
+}
+
+// Solution maps package names to the chosen versions.
+type Solution struct {
+	Packages map[string]*Package
+}
+
+// Names returns the names of every package in the solution in sorted order.
+func (s *Solution) Names() []string {
+	names := make([]string, 0, len(s.Packages))
+	for name := range s.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConflictError explains why no consistent set of versions exists.
+type ConflictError struct {
+	Package      string
+	Requirements []Requirement
+	Available    []string
+	chains       map[string][]step
+}
+
+func (e *ConflictError) Error() string {
+	var sb strings.Builder
+
+	var reasons []string
+	for _, req := range e.Requirements {
+		reasons = append(reasons, fmt.Sprintf("%s requires %s %s", req.describeParent(), e.Package, utils.DescribeConstraint(req.Constraint)))
+	}
+
+	switch {
+	case len(e.Available) == 0:
+		fmt.Fprintf(&sb, "%s, but no versions of %s are published", strings.Join(reasons, " and "), e.Package)
+	case len(reasons) == 1:
+		fmt.Fprintf(&sb, "%s, but no published version matches (available: %s)", reasons[0], strings.Join(e.Available, ", "))
+	default:
+		fmt.Fprintf(&sb, "%s, but %s", strings.Join(reasons[:len(reasons)-1], ", "), reasons[len(reasons)-1])
+	}
+
+	// Explain how each of the conflicting packages was reached from the project
+	for _, req := range e.Requirements {
+		chain := e.chains[req.Parent]
+		if req.Parent == "" || len(chain) == 0 {
+			continue
+		}
+		var steps []string
+		for _, st := range chain {
+			steps = append(steps, fmt.Sprintf("%s requires %s %s", st.requirement.describeParent(), st.name, utils.DescribeConstraint(st.requirement.Constraint)))
+		}
+		fmt.Fprintf(&sb, "\n  %s is required because %s", req.Parent, strings.Join(steps, ", "))
+	}
+
+	return sb.String()
+}
+
+// CycleError reports a dependency cycle in an otherwise consistent solution.
+type CycleError struct {
+	Cycle []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle detected: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// Resolver finds versions for a set of root dependencies.
+type Resolver struct {
+	source Source
+	// Preferred versions are tried first when they satisfy the constraints, which
+	// keeps a lockfile stable when only part of the configuration changed.
+	Preferred map[string]string
+	// Pinned packages are installed from a fixed source such as a git URL. The
+	// pinned version is their only candidate and is accepted whatever other
+	// packages require of it.
+	Pinned map[string]string
+
+	versions     map[string][]string
+	dependencies map[string]map[string]string
+	steps        int
+	conflict     *ConflictError
+}
+
+// New creates a resolver that reads package information from source.
+func New(source Source) *Resolver {
+	return &Resolver{
+		source:       source,
+		Preferred:    map[string]string{},
+		Pinned:       map[string]string{},
+		versions:     map[string][]string{},
+		dependencies: map[string]map[string]string{},
+	}
+}
+
+// state is the partial assignment explored by the solver.
+type state struct {
+	decisions    map[string]*Package
+	requirements map[string][]Requirement
+}
+
+func (s *state) clone() *state {
+	c := &state{
+		decisions:    make(map[string]*Package, len(s.decisions)),
+		requirements: make(map[string][]Requirement, len(s.requirements)),
+	}
+	for name, pkg := range s.decisions {
+		copied := *pkg
+		copied.RequiredBy = append([]Requirement{}, pkg.RequiredBy...)
+		c.decisions[name] = &copied
+	}
+	for name, reqs := range s.requirements {
+		c.requirements[name] = append([]Requirement{}, reqs...)
+	}
+	return c
+}
+
+// Resolve returns a consistent assignment of versions for roots and their dependencies.
+func (r *Resolver) Resolve(roots map[string]string) (*Solution, error) {
+	initial := &state{
+		decisions:    map[string]*Package{},
+		requirements: map[string][]Requirement{},
+	}
+	for name, constraint := range roots {
+		initial.requirements[name] = append(initial.requirements[name], Requirement{Constraint: constraint})
+	}
+
+	r.steps = 0
+	r.conflict = nil
+
+	solved, err := r.solve(initial)
+	if err != nil {
+		return nil, err
+	}
+	if solved == nil {
+		if r.conflict != nil {
+			return nil, r.conflict
+		}
+		return nil, fmt.Errorf("no consistent set of versions found")
+	}
+
+	for name, pkg := range solved.decisions {
+		pkg.RequiredBy = solved.requirements[name]
+	}
+	solution := &Solution{Packages: solved.decisions}
+
+	if cycle := findCycle(solution); cycle != nil {
+		return nil, &CycleError{Cycle: cycle}
+	}
+
+	return solution, nil
+}
+
+// solve returns a complete state, nil when this branch has no solution, or an error from the source.
+func (r *Resolver) solve(s *state) (*state, error) {
+	name, candidates, err := r.nextPackage(s)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return s, nil
+	}
+	if len(candidates) == 0 {
+		r.recordConflict(s, name)
+		return nil, nil
+	}
+
+	for _, version := range candidates {
+		r.steps++
+		if r.steps > maxSteps {
+			return nil, fmt.Errorf("gave up resolving dependencies after trying %d versions", maxSteps)
+		}
+
+		dependencies, err := r.dependenciesOf(name, version)
+		if err != nil {
+			return nil, err
+		}
+
+		next := s.clone()
+		next.decisions[name] = &Package{Name: name, Version: version, Dependencies: dependencies}
+
+		consistent := true
+		for _, dep := range utils.SortedKeys(dependencies) {
+			req := Requirement{Parent: name, ParentVersion: version, Constraint: dependencies[dep]}
+			next.requirements[dep] = append(next.requirements[dep], req)
+
+			// A new requirement on an already decided package must agree with that decision
+			if decided, ok := next.decisions[dep]; ok && r.Pinned[dep] == "" && !utils.Satisfies(decided.Version, req.Constraint) {
+				r.recordConflict(next, dep)
+				consistent = false
+				break
+			}
+		}
+		if !consistent {
+			continue
+		}
+
+		solved, err := r.solve(next)
+		if err != nil || solved != nil {
+			return solved, err
+		}
+	}
+
+	return nil, nil
+}
+
+// nextPackage picks the undecided package with the fewest remaining candidates.
+func (r *Resolver) nextPackage(s *state) (string, []string, error) {
+	var best string
+	var bestCandidates []string
+
+	for _, name := range utils.SortedKeys(s.requirements) {
+		if _, decided := s.decisions[name]; decided {
+			continue
+		}
+
+		candidates, err := r.candidates(name, s.requirements[name])
+		if err != nil {
+			return "", nil, err
+		}
+		if best == "" || len(candidates) < len(bestCandidates) {
+			best, bestCandidates = name, candidates
+		}
+		if len(candidates) == 0 {
+			break
+		}
+	}
+
+	return best, bestCandidates, nil
+}
+
+// candidates returns the versions satisfying every requirement, best first.
+func (r *Resolver) candidates(name string, reqs []Requirement) ([]string, error) {
+	if pinned, ok := r.Pinned[name]; ok {
+		return []string{pinned}, nil
+	}
+
+	versions, err := r.versionsOf(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []string
+	for i := len(versions) - 1; i >= 0; i-- {
+		ok := true
+		for _, req := range reqs {
+			if !utils.Satisfies(versions[i], req.Constraint) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matching = append(matching, versions[i])
+		}
+	}
+
+	if preferred, ok := r.Preferred[name]; ok {
+		for i, version := range matching {
+			if version == preferred {
+				matching = append([]string{version}, append(matching[:i:i], matching[i+1:]...)...)
+				break
+			}
+		}
+	}
+
+	return matching, nil
+}
+
+func (r *Resolver) versionsOf(name string) ([]string, error) {
+	if versions, ok := r.versions[name]; ok {
+		return versions, nil
+	}
+	versions, err := r.source.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+	versions = utils.SortVersions(versions)
+	r.versions[name] = versions
+	return versions, nil
+}
+
+func (r *Resolver) dependenciesOf(name, version string) (map[string]string, error) {
+	key := name + "@" + version
+	if dependencies, ok := r.dependencies[key]; ok {
+		return dependencies, nil
+	}
+	dependencies, err := r.source.Dependencies(name, version)
+	if err != nil {
+		return nil, err
+	}
+	r.dependencies[key] = dependencies
+	return dependencies, nil
+}
+
+// recordConflict remembers the first dead end, which is the one reached by the
+// preferred (highest) versions and therefore the most useful one to report.
+func (r *Resolver) recordConflict(s *state, name string) {
+	if r.conflict != nil {
+		return
+	}
+
+	r.conflict = &ConflictError{
+		Package:      name,
+		Requirements: append([]Requirement{}, s.requirements[name]...),
+		Available:    r.versions[name],
+		chains:       map[string][]step{},
+	}
+	for _, req := range s.requirements[name] {
+		if req.Parent != "" {
+			r.conflict.chains[req.Parent] = chainTo(s, req.Parent)
+		}
+	}
+}
+
+// step is one edge on the path from the project to a package.
+type step struct {
+	requirement Requirement
+	name        string
+}
+
+// chainTo follows the first requirement of each package back to the project.
+func chainTo(s *state, name string) []step {
+	var chain []step
+	seen := map[string]bool{}
+	for name != "" && !seen[name] {
+		seen[name] = true
+		reqs := s.requirements[name]
+		if len(reqs) == 0 {
+			break
+		}
+		chain = append([]step{{requirement: reqs[0], name: name}}, chain...)
+		name = reqs[0].Parent
+	}
+	return chain
+}
+
+// findCycle returns the first dependency cycle in the solution, if any.
+func findCycle(solution *Solution) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	marks := map[string]int{}
+	var stack []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch marks[name] {
+		case visiting:
+			start := 0
+			for i, n := range stack {
+				if n == name {
+					start = i
+				}
+			}
+			return append(append([]string{}, stack[start:]...), name)
+		case done:
+			return nil
+		}
+
+		marks[name] = visiting
+		stack = append(stack, name)
+		pkg := solution.Packages[name]
+		if pkg != nil {
+			for _, dep := range utils.SortedKeys(pkg.Dependencies) {
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		marks[name] = done
+		return nil
+	}
+
+	for _, name := range solution.Names() {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
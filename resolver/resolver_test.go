@@ -0,0 +1,238 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// registry is a Source serving packages from memory: name -> version -> dependencies.
+type registry map[string]map[string]map[string]string
+
+func (r registry) Versions(name string) ([]string, error) {
+	versions, ok := r[name]
+	if !ok {
+		return nil, fmt.Errorf("package %s not found", name)
+	}
+	var list []string
+	for version := range versions {
+		list = append(list, version)
+	}
+	return list, nil
+}
+
+func (r registry) Dependencies(name, version string) (map[string]string, error) {
+	return r[name][version], nil
+}
+
+// versions returns the chosen version of each package in the solution.
+func versions(solution *Solution) map[string]string {
+	chosen := map[string]string{}
+	for name, pkg := range solution.Packages {
+		chosen[name] = pkg.Version
+	}
+	return chosen
+}
+
+func TestResolvePicksHighestMatchingVersions(t *testing.T) {
+	source := registry{
+		"foo": {"1.0.0": nil, "1.4.0": {"bar": "~1.2"}, "2.0.0": nil},
+		"bar": {"1.2.0": nil, "1.2.5": nil, "1.3.0": nil},
+	}
+
+	solution, err := New(source).Resolve(map[string]string{"foo": "^1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"foo": "1.4.0", "bar": "1.2.5"}
+	if got := versions(solution); !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve = %v, want %v", got, want)
+	}
+
+	bar := solution.Packages["bar"]
+	if len(bar.RequiredBy) != 1 || bar.RequiredBy[0].Parent != "foo" || bar.RequiredBy[0].Constraint != "~1.2" {
+		t.Errorf("bar.RequiredBy = %+v, want a single requirement from foo", bar.RequiredBy)
+	}
+	if names := solution.Names(); !reflect.DeepEqual(names, []string{"bar", "foo"}) {
+		t.Errorf("Names = %v, want sorted names", names)
+	}
+}
+
+func TestResolveBacktracks(t *testing.T) {
+	// The newest foo needs bar 2, which baz rules out, so foo has to go back to 1.x
+	source := registry{
+		"foo": {"1.0.0": {"bar": "^1"}, "2.0.0": {"bar": "^2"}},
+		"bar": {"1.0.0": nil, "1.2.0": nil, "2.0.0": nil},
+		"baz": {"1.0.0": {"bar": "^1"}},
+	}
+
+	solution, err := New(source).Resolve(map[string]string{"foo": "*", "baz": "^1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"foo": "1.0.0", "bar": "1.2.0", "baz": "1.0.0"}
+	if got := versions(solution); !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve = %v, want %v", got, want)
+	}
+}
+
+func TestResolvePreferredAndPinned(t *testing.T) {
+	source := registry{
+		"foo": {"1.0.0": {"bar": "^2"}, "1.1.0": {"bar": "^2"}},
+		"bar": {"2.0.0": nil},
+	}
+
+	r := New(source)
+	// A locked version is kept while it still satisfies the constraint
+	r.Preferred["foo"] = "1.0.0"
+	// A pinned package is accepted whatever its dependents require
+	r.Pinned["bar"] = "0.0.0-git"
+
+	solution, err := r.Resolve(map[string]string{"foo": "^1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"foo": "1.0.0", "bar": "0.0.0-git"}
+	if got := versions(solution); !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve = %v, want %v", got, want)
+	}
+
+	// A preferred version that no longer matches is dropped
+	r = New(source)
+	r.Preferred["foo"] = "0.9.0"
+	solution, err = r.Resolve(map[string]string{"foo": "^1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := solution.Packages["foo"].Version; got != "1.1.0" {
+		t.Errorf("foo = %s, want 1.1.0", got)
+	}
+}
+
+func TestResolveConflictExplainsChain(t *testing.T) {
+	// app -> foo -> qux ^1 conflicts with app -> baz -> qux ^2
+	source := registry{
+		"foo": {"1.0.0": {"qux": "^1"}},
+		"baz": {"1.0.0": {"qux": "^2"}},
+		"qux": {"1.0.0": nil, "2.0.0": nil},
+	}
+
+	_, err := New(source).Resolve(map[string]string{"foo": "^1", "baz": "^1"})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Resolve error = %v, want a *ConflictError", err)
+	}
+	if conflict.Package != "qux" {
+		t.Errorf("conflict on %s, want qux", conflict.Package)
+	}
+
+	message := err.Error()
+	for _, want := range []string{
+		"baz@1.0.0 requires qux ^2",
+		"foo@1.0.0 requires qux ^1",
+		"is required because the project requires",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("conflict message %q does not mention %q", message, want)
+		}
+	}
+}
+
+func TestResolveNoMatchingVersion(t *testing.T) {
+	source := registry{
+		"foo": {"1.0.0": nil, "1.1.0": nil},
+		"bar": {},
+	}
+
+	_, err := New(source).Resolve(map[string]string{"foo": "^2"})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Resolve error = %v, want a *ConflictError", err)
+	}
+	want := "the project requires foo ^2, but no published version matches (available: 1.0.0, 1.1.0)"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+
+	_, err = New(source).Resolve(map[string]string{"bar": ""})
+	want = "the project requires bar any version, but no versions of bar are published"
+	if err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
+
+func TestResolvePrereleases(t *testing.T) {
+	source := registry{
+		"foo": {"1.0.0": nil, "1.1.0-beta.1": nil, "2.0.0-rc.1": nil},
+	}
+
+	solution, err := New(source).Resolve(map[string]string{"foo": "*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := solution.Packages["foo"].Version; got != "1.0.0" {
+		t.Errorf("foo = %s, want the newest release 1.0.0", got)
+	}
+
+	solution, err = New(source).Resolve(map[string]string{"foo": "^2.0.0-rc.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := solution.Packages["foo"].Version; got != "2.0.0-rc.1" {
+		t.Errorf("foo = %s, want 2.0.0-rc.1", got)
+	}
+}
+
+func TestResolveRockspecConstraints(t *testing.T) {
+	source := registry{
+		"lpeg": {"1.0.0": nil, "1.0.2": nil, "1.1.0": nil},
+		"app":  {"1.0.0": {"lpeg": "~> 1.0"}},
+	}
+
+	solution, err := New(source).Resolve(map[string]string{"app": ">= 1.0, < 2.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := solution.Packages["lpeg"].Version; got != "1.0.2" {
+		t.Errorf("lpeg = %s, want 1.0.2", got)
+	}
+}
+
+func TestResolveCycle(t *testing.T) {
+	source := registry{
+		"foo": {"1.0.0": {"bar": "^1"}},
+		"bar": {"1.0.0": {"baz": "^1"}},
+		"baz": {"1.0.0": {"foo": "^1"}},
+	}
+
+	_, err := New(source).Resolve(map[string]string{"foo": "^1"})
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("Resolve error = %v, want a *CycleError", err)
+	}
+	want := []string{"bar", "baz", "foo", "bar"}
+	if !reflect.DeepEqual(cycle.Cycle, want) {
+		t.Errorf("cycle = %v, want %v", cycle.Cycle, want)
+	}
+}
+
+func TestResolveSourceError(t *testing.T) {
+	source := registry{"foo": {"1.0.0": {"missing": "^1"}}}
+
+	_, err := New(source).Resolve(map[string]string{"foo": "^1"})
+	if err == nil || !strings.Contains(err.Error(), "package missing not found") {
+		t.Errorf("Resolve error = %v, want the source error", err)
+	}
+}
+
+func TestResolveEmpty(t *testing.T) {
+	solution, err := New(registry{}).Resolve(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(solution.Packages) != 0 {
+		t.Errorf("Resolve(nil) = %v, want no packages", versions(solution))
+	}
+}
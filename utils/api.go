@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"nep/configs"
-	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 )
@@ -17,6 +17,8 @@ type Response struct {
 	Data              Data              `json:"data"`
 	Key               string            `json:"key"`
 	TemporalSemantics TemporalSemantics `json:"temporal_semantics"`
+	// Commit is set on the copy saved inside an installed package
+	Commit string `json:"commit,omitempty"`
 }
 
 // Data represents the data part of the API response.
@@ -27,7 +29,10 @@ type Data struct {
 	ScanResponse struct {
 		Lua string `json:"lua"`
 	} `json:"scanResponse"`
-	Version string `json:"version"`
+	Version      string            `json:"version"`
+	Description  string            `json:"description,omitempty"`
+	Ref          string            `json:"ref,omitempty"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
 }
 
 // TemporalSemantics represents the temporal semantics part of the API response.
@@ -35,38 +40,19 @@ type TemporalSemantics struct {
 	LatestGetRequest string `json:"latest-get-request"`
 }
 
-// FetchPackageData fetches data from the API for a given package.
-func FetchPackageData(packageName string) (*Response, error) {
-	var apiUrl string
-	parts := strings.Split(packageName, "::")
-	if len(parts) == 2 {
-		apiUrl = fmt.Sprintf("%s/api/%s:v%s", configs.APIBaseURL, parts[0], parts[1])
-	} else {
-		apiUrl = fmt.Sprintf("%s/api/%s", configs.APIBaseURL, packageName)
+// SplitPackageArg splits a "name::constraint" argument into its name and constraint.
+// A bare source specifier such as github:owner/repo#tag is named after its repository.
+func SplitPackageArg(pkg string) (string, string) {
+	name, constraint, _ := strings.Cut(pkg, "::")
+	if spec, ok := ParseSourceSpecifier(name); ok && constraint == "" {
+		return spec.Name(), name
 	}
-
-	resp, err := http.Get(apiUrl)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch data from API for %s: %s", packageName, err)
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read API response body for %s: %s", packageName, err)
-	}
-
-	var responseData Response
-	if err := json.Unmarshal(body, &responseData); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON for %s: %s", packageName, err)
-	}
-
-	return &responseData, nil
+	return name, constraint
 }
 
 // SaveResponseToFile saves API response JSON to a file with proper indentation.
 func SaveResponseToFile(responseData *Response, clonePath string) error {
-	responseJSON, err := json.MarshalIndent(responseData, "", "  ")
+	responseJSON, err := marshalIndent(responseData)
 	if err != nil {
 		return fmt.Errorf("failed to serialize API response: %s", err)
 	}
@@ -78,3 +64,29 @@ func SaveResponseToFile(responseData *Response, clonePath string) error {
 
 	return nil
 }
+
+// ReadSavedResponse reads the API response saved inside an installed package.
+func ReadSavedResponse(packageDir string) (*Response, error) {
+	responseFilePath := filepath.Join(packageDir, configs.ResponseFileName+".json")
+
+	responseJSON, err := os.ReadFile(responseFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var responseData Response
+	if err := json.Unmarshal(responseJSON, &responseData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", responseFilePath, err)
+	}
+
+	return &responseData, nil
+}
+
+// InstalledCommit returns the commit an installed package was populated from.
+func InstalledCommit(packageDir string) (string, error) {
+	if responseData, err := ReadSavedResponse(packageDir); err == nil && responseData.Commit != "" {
+		return responseData.Commit, nil
+	}
+	// Packages installed before the store was introduced are plain git clones
+	return HeadCommit(packageDir)
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Credentials are the tokens saved by nep login, in ~/.config/nep/credentials.json.
+// Registries are keyed by URL and git hosts by host name. The file is only
+// readable by the user:
+//
+//	{
+//	  "registries": {"https://registry.studio.internal": {"token": "..."}},
+//	  "hosts": {"git.studio.internal": {"username": "ci", "token": "..."}}
+//	}
+type Credentials struct {
+	Registries map[string]Credential `json:"registries"`
+	Hosts      map[string]Credential `json:"hosts,omitempty"`
+}
+
+// Credential is the login of a single registry or git host.
+type Credential struct {
+	Username string `json:"username,omitempty"`
+	Token    string `json:"token"`
+}
+
+// CredentialsPath returns where the credentials file lives.
+func CredentialsPath() (string, error) {
+	configDir, err := UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "credentials.json"), nil
+}
+
+// ReadCredentials reads the credentials file. A missing file has no credentials.
+func ReadCredentials() (*Credentials, error) {
+	credentials := &Credentials{}
+
+	credentialsPath, err := CredentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(credentialsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read credentials: %v", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, credentials); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", credentialsPath, err)
+		}
+	}
+
+	if credentials.Registries == nil {
+		credentials.Registries = map[string]Credential{}
+	}
+	if credentials.Hosts == nil {
+		credentials.Hosts = map[string]Credential{}
+	}
+	return credentials, nil
+}
+
+// Write saves the credentials with 0600 permissions.
+func (c *Credentials) Write() error {
+	credentialsPath, err := CredentialsPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(credentialsPath), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(credentialsPath), err)
+	}
+
+	// CreateTemp makes the file 0600, so the token is never readable by others
+	tmp, err := os.CreateTemp(filepath.Dir(credentialsPath), ".credentials-*")
+	if err != nil {
+		return fmt.Errorf("failed to write credentials: %v", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), credentialsPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write credentials: %v", err)
+	}
+	return nil
+}
+
+// RegistryToken returns the stored token of the registry at baseURL, if any.
+func (c *Credentials) RegistryToken(baseURL string) string {
+	if c == nil {
+		return ""
+	}
+	return c.Registries[strings.TrimRight(baseURL, "/")].Token
+}
+
+// SetRegistry stores the token of a registry.
+func (c *Credentials) SetRegistry(baseURL, token string) {
+	c.Registries[strings.TrimRight(baseURL, "/")] = Credential{Token: token}
+}
+
+// RemoveRegistry forgets the token of a registry and reports whether there was one.
+func (c *Credentials) RemoveRegistry(baseURL string) bool {
+	return remove(c.Registries, strings.TrimRight(baseURL, "/"))
+}
+
+// SetHost stores the login used to clone over HTTPS from a git host.
+func (c *Credentials) SetHost(host, username, token string) {
+	c.Hosts[strings.ToLower(host)] = Credential{Username: username, Token: token}
+}
+
+// RemoveHost forgets the login of a git host and reports whether there was one.
+func (c *Credentials) RemoveHost(host string) bool {
+	return remove(c.Hosts, strings.ToLower(host))
+}
+
+func remove(credentials map[string]Credential, key string) bool {
+	_, ok := credentials[key]
+	delete(credentials, key)
+	return ok
+}
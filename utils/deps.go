@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"nep/configs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ReadPackageDependencies returns the dependencies declared by an installed package.
+// A nebula-config.json takes precedence; otherwise the first rockspec found is used.
+func ReadPackageDependencies(packageDir string) (map[string]string, error) {
+	configFilePath := filepath.Join(packageDir, configs.JSONName+".json")
+	if _, err := os.Stat(configFilePath); err == nil {
+		return readConfigDependencies(configFilePath)
+	}
+
+	rockspecs, err := filepath.Glob(filepath.Join(packageDir, "*.rockspec"))
+	if err != nil {
+		return nil, err
+	}
+	if len(rockspecs) == 0 {
+		rockspecs, _ = filepath.Glob(filepath.Join(packageDir, "rockspecs", "*.rockspec"))
+	}
+	if len(rockspecs) == 0 {
+		return map[string]string{}, nil
+	}
+
+	return readRockspecDependencies(rockspecs[len(rockspecs)-1])
+}
+
+func readConfigDependencies(configFilePath string) (map[string]string, error) {
+	configFileBytes, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", configFilePath, err)
+	}
+
+	var config struct {
+		Dependencies map[string]interface{} `json:"dependencies"`
+	}
+	if err := json.Unmarshal(configFileBytes, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", configFilePath, err)
+	}
+
+	dependencies := map[string]string{}
+	for name, version := range config.Dependencies {
+		versionStr, ok := version.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid version format for dependency %s in %s: %v", name, configFilePath, version)
+		}
+		dependencies[name] = versionStr
+	}
+
+	return dependencies, nil
+}
+
+// readRockspecDependencies evaluates a rockspec without any standard libraries
+// and converts entries such as "penlight >= 1.5, < 2.0" into name/constraint pairs.
+func readRockspecDependencies(rockspecPath string) (map[string]string, error) {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+
+	if err := L.DoFile(rockspecPath); err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %v", rockspecPath, err)
+	}
+
+	dependencies := map[string]string{}
+	table, ok := L.GetGlobal("dependencies").(*lua.LTable)
+	if !ok {
+		return dependencies, nil
+	}
+
+	table.ForEach(func(_, value lua.LValue) {
+		fields := strings.Fields(value.String())
+		if len(fields) == 0 {
+			return
+		}
+		name := fields[0]
+		// The Lua interpreter itself is not a package
+		if name == "lua" {
+			return
+		}
+		dependencies[name] = strings.Join(fields[1:], " ")
+	})
+
+	return dependencies, nil
+}
+
+// ReadPackageVersion returns the version a package declares in its nebula-config.json
+// or rockspec, or fallback when it declares none.
+func ReadPackageVersion(packageDir, fallback string) string {
+	var config struct {
+		Version string `json:"version"`
+	}
+	if configFileBytes, err := os.ReadFile(filepath.Join(packageDir, configs.JSONName+".json")); err == nil {
+		if json.Unmarshal(configFileBytes, &config) == nil && config.Version != "" {
+			return config.Version
+		}
+	}
+
+	rockspecs, _ := filepath.Glob(filepath.Join(packageDir, "*.rockspec"))
+	if len(rockspecs) == 0 {
+		return fallback
+	}
+
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+	if err := L.DoFile(rockspecs[len(rockspecs)-1]); err != nil {
+		return fallback
+	}
+	version, ok := L.GetGlobal("version").(lua.LString)
+	if !ok || version == "" {
+		return fallback
+	}
+
+	// Drop the rockspec revision, as in "3.1.3-1"
+	if i := strings.LastIndex(string(version), "-"); i > 0 {
+		return string(version)[:i]
+	}
+	return string(version)
+}
+
+// SortedKeys returns the keys of a map in sorted order, for deterministic output.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// VersionRefs returns the refs to try, in order, when checking out a package version.
+func VersionRefs(data Data) []string {
+	var refs []string
+	if data.Ref != "" {
+		refs = append(refs, data.Ref)
+	}
+	if version := strings.TrimPrefix(data.Version, "v"); version != "" {
+		refs = append(refs, "v"+version, version)
+	}
+	return refs
+}
+
+// resolveRefs returns the commit of the first ref in refs that exists in repo.
+func resolveRefs(repo *git.Repository, refs []string) (plumbing.Hash, error) {
+	for _, ref := range refs {
+		hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+		if err == nil {
+			return *hash, nil
+		}
+	}
+	return plumbing.ZeroHash, fmt.Errorf("no ref matching %s", strings.Join(refs, ", "))
+}
+
+// HeadCommit returns the commit SHA currently checked out in dir.
+func HeadCommit(dir string) (string, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+
+	return head.Hash().String(), nil
+}
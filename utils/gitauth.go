@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// SSHPassphraseEnv holds the passphrase of an encrypted key set with ssh_key.
+const SSHPassphraseEnv = "NEP_SSH_KEY_PASSPHRASE"
+
+// GitSettings are the "git" keys of the user config, which tell nep how to
+// authenticate clones of private package repositories:
+//
+//	"git": {
+//	  "ssh_key": "~/.ssh/id_ed25519",
+//	  "hosts": {
+//	    "git.studio.internal": {"username": "build", "ssh_key": "~/.ssh/studio"}
+//	  }
+//	}
+//
+// HTTPS tokens are not kept here but in the credentials file, see nep login --git.
+type GitSettings struct {
+	// SSHKey is the private key used for hosts without their own
+	SSHKey string             `json:"ssh_key,omitempty"`
+	Hosts  map[string]GitHost `json:"hosts,omitempty"`
+}
+
+// GitHost configures the clones from a single host.
+type GitHost struct {
+	// Username is the SSH user, and the HTTPS user when the stored login has none
+	Username string `json:"username,omitempty"`
+	SSHKey   string `json:"ssh_key,omitempty"`
+}
+
+// GitAuth returns the credentials to clone or fetch the repository at url
+// with, or nil to let git connect without any. In order, it uses:
+//
+//   - for SSH, the configured key of the host or the default ssh_key, then
+//     ssh-agent, then ~/.ssh/id_ed25519, id_ecdsa or id_rsa
+//   - for HTTPS, credentials embedded in the URL, then the login stored for
+//     the host by nep login --git, then the host's entry in ~/.netrc
+func GitAuth(url string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		// Leave reporting the malformed URL to the clone itself
+		return nil, nil
+	}
+
+	var config struct {
+		Git GitSettings `json:"git"`
+	}
+	if err := ReadUserConfig(&config); err != nil {
+		return nil, err
+	}
+	hostName := strings.ToLower(endpoint.Host)
+	host := config.Git.Hosts[hostName]
+
+	switch endpoint.Protocol {
+	case "ssh":
+		user := endpoint.User
+		if user == "" {
+			user = host.Username
+		}
+		if user == "" {
+			user = "git"
+		}
+		keyPath := host.SSHKey
+		if keyPath == "" {
+			keyPath = config.Git.SSHKey
+		}
+		return sshAuth(user, keyPath)
+
+	case "http", "https":
+		if endpoint.User != "" {
+			return nil, nil
+		}
+
+		credentials, err := ReadCredentials()
+		if err != nil {
+			return nil, err
+		}
+		if login, ok := credentials.Hosts[hostName]; ok {
+			username := login.Username
+			if username == "" {
+				username = host.Username
+			}
+			if username == "" {
+				// Hosts that take tokens as passwords accept any user name
+				username = "git"
+			}
+			return &githttp.BasicAuth{Username: username, Password: login.Token}, nil
+		}
+
+		if login, password, ok := netrcLogin(endpoint.Host); ok {
+			return &githttp.BasicAuth{Username: login, Password: password}, nil
+		}
+	}
+	return nil, nil
+}
+
+// sshAuth loads the configured key, or else falls back to ssh-agent and the default keys.
+func sshAuth(user, keyPath string) (transport.AuthMethod, error) {
+	if keyPath != "" {
+		keyPath = expandHome(keyPath)
+		auth, err := gitssh.NewPublicKeysFromFile(user, keyPath, os.Getenv(SSHPassphraseEnv))
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("SSH key %s does not exist", keyPath)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to load SSH key %s: %v (encrypted keys need %s or ssh-agent)", keyPath, err, SSHPassphraseEnv)
+		}
+		return auth, nil
+	}
+
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		if auth, err := gitssh.NewSSHAgentAuth(user); err == nil {
+			return auth, nil
+		}
+	}
+
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		defaultKey := expandHome(filepath.Join("~", ".ssh", name))
+		if _, err := os.Stat(defaultKey); err != nil {
+			continue
+		}
+		// Default keys that need a passphrase are skipped rather than failing the clone
+		if auth, err := gitssh.NewPublicKeysFromFile(user, defaultKey, os.Getenv(SSHPassphraseEnv)); err == nil {
+			return auth, nil
+		}
+	}
+	return nil, nil
+}
+
+// GitAuthError explains a clone that failed for lack of credentials, and returns other errors as they are.
+func GitAuthError(url string, err error) error {
+	if !errors.Is(err, transport.ErrAuthenticationRequired) && !errors.Is(err, transport.ErrAuthorizationFailed) {
+		return err
+	}
+
+	host := url
+	if endpoint, endpointErr := transport.NewEndpoint(url); endpointErr == nil {
+		host = endpoint.Host
+	}
+	return fmt.Errorf("%v for %s: run nep login --git %s, add %s to ~/.netrc, or set up an SSH key for it in the git section of the nep config",
+		err, url, host, host)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"nep/configs"
+)
+
+// IntegrityPrefix marks the hash algorithm of an integrity value.
+const IntegrityPrefix = "sha256-"
+
+// FileHashes returns the sha256 of every file below dir, keyed by slash separated
+// relative path. Symlinks are hashed by their target. .git directories and the
+// metadata nep saves next to a package are not part of its contents.
+func FileHashes(dir string) (map[string]string, error) {
+	hashes := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == configs.ResponseFileName+".json" {
+			return nil
+		}
+
+		h := sha256.New()
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			io.WriteString(h, link)
+		} else {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		hashes[filepath.ToSlash(rel)] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %v", dir, err)
+	}
+	return hashes, nil
+}
+
+// HashFiles combines file hashes into a single tree hash over the sorted paths.
+func HashFiles(hashes map[string]string) string {
+	paths := make([]string, 0, len(hashes))
+	for path := range hashes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "%s\x00%s\n", path, hashes[path])
+	}
+	return IntegrityPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// TreeHash returns the deterministic hash of the contents of dir.
+func TreeHash(dir string) (string, error) {
+	hashes, err := FileHashes(dir)
+	if err != nil {
+		return "", err
+	}
+	return HashFiles(hashes), nil
+}
+
+// TreeDiff lists the files that differ between two trees.
+type TreeDiff struct {
+	Modified []string
+	Missing  []string
+	Extra    []string
+}
+
+// DiffFiles compares the file hashes of an actual tree with the expected ones.
+func DiffFiles(expected, actual map[string]string) TreeDiff {
+	var diff TreeDiff
+	for path, hash := range expected {
+		actualHash, ok := actual[path]
+		switch {
+		case !ok:
+			diff.Missing = append(diff.Missing, path)
+		case actualHash != hash:
+			diff.Modified = append(diff.Modified, path)
+		}
+	}
+	for path := range actual {
+		if _, ok := expected[path]; !ok {
+			diff.Extra = append(diff.Extra, path)
+		}
+	}
+
+	sort.Strings(diff.Modified)
+	sort.Strings(diff.Missing)
+	sort.Strings(diff.Extra)
+	return diff
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"nep/configs"
+	"os"
+	"path/filepath"
+)
+
+// LockfileVersion is the format version written to nebula-lock.json.
+const LockfileVersion = 1
+
+// LockedPackage records exactly what was installed for a single dependency.
+type LockedPackage struct {
+	Key       string `json:"key"`
+	Specifier string `json:"specifier"`
+	GithubURL string `json:"github_url"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	// Integrity is the TreeHash of the installed files
+	Integrity    string            `json:"integrity,omitempty"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+	// Dev marks packages only needed through devDependencies, which production installs leave out
+	Dev bool `json:"dev,omitempty"`
+}
+
+// Response rebuilds the registry metadata of a locked package for saving inside nebpack/.
+func (p LockedPackage) Response() *Response {
+	return &Response{
+		Key: p.Key,
+		Data: Data{
+			GithubURL:    p.GithubURL,
+			Version:      p.Version,
+			Dependencies: p.Dependencies,
+		},
+		Commit: p.Commit,
+	}
+}
+
+// Lockfile represents the structure of nebula-lock.json.
+type Lockfile struct {
+	LockfileVersion int                      `json:"lockfileVersion"`
+	Packages        map[string]LockedPackage `json:"packages"`
+}
+
+// LockfileExists reports whether the project has a nebula-lock.json file.
+func LockfileExists(projectDir string) bool {
+	_, err := os.Stat(filepath.Join(projectDir, configs.LockFileName+".json"))
+	return err == nil
+}
+
+// ReadLockfile reads nebula-lock.json, returning an empty lockfile if it does not exist.
+func ReadLockfile(projectDir string) (*Lockfile, error) {
+	lockFilePath := filepath.Join(projectDir, configs.LockFileName+".json")
+
+	lock := &Lockfile{
+		LockfileVersion: LockfileVersion,
+		Packages:        map[string]LockedPackage{},
+	}
+
+	lockFileBytes, err := os.ReadFile(lockFilePath)
+	if os.IsNotExist(err) {
+		return lock, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %v", err)
+	}
+
+	if err := json.Unmarshal(lockFileBytes, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file: %v", err)
+	}
+	if lock.Packages == nil {
+		lock.Packages = map[string]LockedPackage{}
+	}
+
+	return lock, nil
+}
+
+// WriteLockfile writes the lockfile next to nebula-config.json.
+func WriteLockfile(projectDir string, lock *Lockfile) error {
+	lockFilePath := filepath.Join(projectDir, configs.LockFileName+".json")
+
+	lock.LockfileVersion = LockfileVersion
+	lockFileBytes, err := marshalIndent(lock)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock file: %v", err)
+	}
+
+	if err := os.WriteFile(lockFilePath, lockFileBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateLockfile sets or removes entries in nebula-lock.json.
+// A nil entry removes the package from the lockfile.
+func UpdateLockfile(projectDir string, entries map[string]*LockedPackage) error {
+	lock, err := ReadLockfile(projectDir)
+	if err != nil {
+		return err
+	}
+
+	for name, entry := range entries {
+		if entry == nil {
+			delete(lock.Packages, name)
+		} else {
+			lock.Packages[name] = *entry
+		}
+	}
+
+	return WriteLockfile(projectDir, lock)
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// netrcLogin looks up the login and password for host in the user's .netrc,
+// ~/.netrc (~/_netrc on Windows) unless NETRC names another file. A "default"
+// entry applies to hosts without their own entry.
+func netrcLogin(host string) (string, string, bool) {
+	netrcPath := os.Getenv("NETRC")
+	if netrcPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false
+		}
+		name := ".netrc"
+		if runtime.GOOS == "windows" {
+			name = "_netrc"
+		}
+		netrcPath = filepath.Join(home, name)
+	}
+
+	data, err := os.ReadFile(netrcPath)
+	if err != nil {
+		return "", "", false
+	}
+	return parseNetrc(string(data), host)
+}
+
+func parseNetrc(data, host string) (string, string, bool) {
+	type entry struct{ login, password string }
+	var (
+		current  *entry
+		matched  *entry
+		fallback *entry
+		inMacro  bool
+		tokens   []string
+	)
+
+	// Macro definitions run until the next blank line, so tokens are gathered line by line
+	for _, line := range strings.Split(data, "\n") {
+		if inMacro {
+			if strings.TrimSpace(line) == "" {
+				inMacro = false
+			}
+			continue
+		}
+		lineTokens := strings.Fields(line)
+		for i := 0; i < len(lineTokens); i++ {
+			if strings.HasPrefix(lineTokens[i], "#") {
+				break
+			}
+			if lineTokens[i] == "macdef" {
+				inMacro = true
+				break
+			}
+			tokens = append(tokens, lineTokens[i])
+		}
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		switch tokens[i] {
+		case "machine":
+			current = &entry{}
+			if matched == nil && strings.EqualFold(next, host) {
+				matched = current
+			}
+			i++
+		case "default":
+			current = &entry{}
+			if fallback == nil {
+				fallback = current
+			}
+		case "login":
+			if current != nil {
+				current.login = next
+			}
+			i++
+		case "password":
+			if current != nil {
+				current.password = next
+			}
+			i++
+		case "account":
+			i++
+		}
+	}
+
+	if matched == nil {
+		matched = fallback
+	}
+	if matched == nil || matched.password == "" {
+		return "", "", false
+	}
+	return matched.login, matched.password, true
+}
@@ -0,0 +1,102 @@
+package utils
+
+import "testing"
+
+func TestParseNetrc(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		host     string
+		login    string
+		password string
+		ok       bool
+	}{
+		{
+			name:     "single line",
+			data:     "machine git.example.com login build password tok123",
+			host:     "git.example.com",
+			login:    "build",
+			password: "tok123",
+			ok:       true,
+		},
+		{
+			name: "one token per line",
+			data: `machine other.example.com
+  login someone
+  password other
+
+machine git.example.com
+  login build
+  password tok123
+`,
+			host:     "git.example.com",
+			login:    "build",
+			password: "tok123",
+			ok:       true,
+		},
+		{
+			name:     "host is case insensitive",
+			data:     "machine Git.Example.com login build password tok123",
+			host:     "git.example.com",
+			login:    "build",
+			password: "tok123",
+			ok:       true,
+		},
+		{
+			name:     "default entry",
+			data:     "machine other.example.com login someone password other\ndefault login anonymous password guest",
+			host:     "git.example.com",
+			login:    "anonymous",
+			password: "guest",
+			ok:       true,
+		},
+		{
+			name:     "machine entry wins over an earlier default",
+			data:     "default login anonymous password guest\nmachine git.example.com login build password tok123",
+			host:     "git.example.com",
+			login:    "build",
+			password: "tok123",
+			ok:       true,
+		},
+		{
+			name:     "account and comments are skipped",
+			data:     "# CI credentials\nmachine git.example.com account ops login build # the bot\npassword tok123",
+			host:     "git.example.com",
+			login:    "build",
+			password: "tok123",
+			ok:       true,
+		},
+		{
+			name: "macro definitions are skipped",
+			data: `macdef init
+machine git.example.com login evil password stolen
+
+machine git.example.com login build password tok123
+`,
+			host:     "git.example.com",
+			login:    "build",
+			password: "tok123",
+			ok:       true,
+		},
+		{
+			name: "entry without a password",
+			data: "machine git.example.com login build",
+			host: "git.example.com",
+		},
+		{
+			name: "no matching entry",
+			data: "machine other.example.com login someone password other",
+			host: "git.example.com",
+		},
+		{
+			name: "empty file",
+			host: "git.example.com",
+		},
+	}
+	for _, tt := range tests {
+		login, password, ok := parseNetrc(tt.data, tt.host)
+		if login != tt.login || password != tt.password || ok != tt.ok {
+			t.Errorf("%s: parseNetrc = %q, %q, %v, want %q, %q, %v", tt.name, login, password, ok, tt.login, tt.password, tt.ok)
+		}
+	}
+}
@@ -2,10 +2,14 @@ package utils
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"text/tabwriter"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
 )
 
 var (
@@ -127,7 +131,52 @@ func min(a, b int) int {
 	return b
 }
 
+// TreeNode is a line of the output of RenderTree and the lines nested under it.
+type TreeNode struct {
+	Label    string
+	Children []TreeNode
+}
+
+// RenderTree draws root and its children, connected with the box-drawing
+// characters of the Table borders:
+//
+//	project
+//	├── foo
+//	│   └── bar
+//	└── baz
+func RenderTree(root TreeNode) string {
+	var sb strings.Builder
+	sb.WriteString(root.Label)
+	sb.WriteString("\n")
+	renderChildren(&sb, root.Children, "")
+	return sb.String()
+}
+
+func renderChildren(sb *strings.Builder, children []TreeNode, prefix string) {
+	for i, child := range children {
+		branch, indent := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		sb.WriteString(tableBorderStyle.Render(prefix + branch))
+		sb.WriteString(child.Label)
+		sb.WriteString("\n")
+		renderChildren(sb, child.Children, prefix+indent)
+	}
+}
+
+// IsTerminal reports whether stdout is a terminal, which the interactive views need.
+func IsTerminal() bool {
+	return isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+}
+
+// DisplayTable shows rows in a scrollable table, or prints them as plain
+// columns when stdout is not a terminal.
 func DisplayTable(headers []string, rows [][]string) error {
+	if !IsTerminal() {
+		return printTable(os.Stdout, headers, rows)
+	}
+
 	table := Table{
 		Headers:        headers,
 		Rows:           rows,
@@ -137,3 +186,13 @@ func DisplayTable(headers []string, rows [][]string) error {
 	_, err := p.Run()
 	return err
 }
+
+// printTable writes the rows as tab aligned columns, for pipes and scripts.
+func printTable(w io.Writer, headers []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	progressNameStyle   = lipgloss.NewStyle().Bold(true)
+	progressDetailStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	progressPhaseStyles = map[Phase]lipgloss.Style{
+		PhaseResolving:   lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
+		PhaseCloning:     lipgloss.NewStyle().Foreground(lipgloss.Color("33")),
+		PhaseCheckingOut: lipgloss.NewStyle().Foreground(lipgloss.Color("33")),
+		PhaseLinking:     lipgloss.NewStyle().Foreground(lipgloss.Color("33")),
+		PhaseDone:        lipgloss.NewStyle().Foreground(lipgloss.Color("42")),
+		PhaseFailed:      lipgloss.NewStyle().Foreground(lipgloss.Color("196")),
+	}
+)
+
+// Phase is the step a package has reached during an install.
+type Phase int
+
+const (
+	PhaseResolving Phase = iota
+	PhaseCloning
+	PhaseCheckingOut
+	PhaseLinking
+	PhaseDone
+	PhaseFailed
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseResolving:
+		return "resolving"
+	case PhaseCloning:
+		return "cloning"
+	case PhaseCheckingOut:
+		return "checking out"
+	case PhaseLinking:
+		return "linking"
+	case PhaseDone:
+		return "done"
+	case PhaseFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// phaseSetter is implemented by progress writers that want to know when a
+// store operation moves past the git transfer.
+type phaseSetter interface {
+	SetPhase(phase Phase)
+}
+
+// setPhase reports phase to progress if it is interested.
+func setPhase(progress io.Writer, phase Phase) {
+	if p, ok := progress.(phaseSetter); ok {
+		p.SetPhase(phase)
+	}
+}
+
+type progressRow struct {
+	name   string
+	phase  Phase
+	detail string
+}
+
+type progressMsg progressRow
+
+// Progress is a live view with one row per package, in the style of Table.
+// When stdout is not a terminal it falls back to plain log lines.
+type Progress struct {
+	Title   string
+	program *tea.Program
+	done    chan struct{}
+	stopped bool
+	mu      sync.Mutex
+	phases  map[string]Phase
+}
+
+// NewProgress creates a progress view; call Start before reporting and Stop at the end.
+func NewProgress(title string) *Progress {
+	return &Progress{Title: title, phases: map[string]Phase{}}
+}
+
+// Start shows the view. It does nothing when stdout is not a terminal.
+func (p *Progress) Start() {
+	if !IsTerminal() {
+		return
+	}
+
+	// Without input the terminal stays in cooked mode, so Ctrl-C still raises SIGINT
+	p.program = tea.NewProgram(progressModel{title: p.Title, index: map[string]int{}},
+		tea.WithInput(nil), tea.WithoutSignalHandler())
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		p.program.Run()
+	}()
+}
+
+// Stop renders the final state and gives the terminal back. It is safe to call more than once.
+func (p *Progress) Stop() {
+	if p == nil || p.program == nil {
+		return
+	}
+
+	p.mu.Lock()
+	stopped := p.stopped
+	p.stopped = true
+	p.mu.Unlock()
+	if stopped {
+		return
+	}
+
+	p.program.Quit()
+	<-p.done
+}
+
+// Set moves a package to phase, with detail shown next to it.
+func (p *Progress) Set(name string, phase Phase, detail string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.program != nil {
+		if !p.stopped {
+			p.program.Send(progressMsg{name: name, phase: phase, detail: detail})
+		}
+		return
+	}
+
+	// Plain logs get a line when a package starts cloning and when it is done;
+	// failures are left to the caller's summary
+	previous, seen := p.phases[name]
+	p.phases[name] = phase
+	switch {
+	case phase == PhaseCloning && (!seen || previous != PhaseCloning):
+		fmt.Printf("%s: cloning\n", name)
+	case phase == PhaseDone:
+		fmt.Printf("%s: %s\n", name, detail)
+	}
+}
+
+// Log prints a message above the view, or as a plain line.
+func (p *Progress) Log(format string, args ...interface{}) {
+	if p != nil {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+	}
+	if p == nil || p.program == nil || p.stopped {
+		fmt.Printf(format+"\n", args...)
+		return
+	}
+	p.program.Printf(format, args...)
+}
+
+// Writer returns the writer to pass as git progress for a package. The
+// transfer counters git reports are shown in the package's row.
+func (p *Progress) Writer(name string) io.Writer {
+	if p == nil {
+		return nil
+	}
+	return &progressWriter{progress: p, name: name}
+}
+
+type progressWriter struct {
+	progress *Progress
+	name     string
+	pending  string
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	// git separates updates of a counter with \r and finished counters with \n
+	lines := strings.FieldsFunc(w.pending+string(b), func(r rune) bool { return r == '\r' || r == '\n' })
+	w.pending = ""
+	if len(lines) == 0 {
+		return len(b), nil
+	}
+	if last := b[len(b)-1]; last != '\r' && last != '\n' {
+		w.pending = lines[len(lines)-1]
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 0 {
+		w.progress.Set(w.name, PhaseCloning, strings.TrimSpace(lines[len(lines)-1]))
+	}
+	return len(b), nil
+}
+
+func (w *progressWriter) SetPhase(phase Phase) {
+	w.progress.Set(w.name, phase, "")
+}
+
+type progressModel struct {
+	title string
+	rows  []progressRow
+	index map[string]int
+}
+
+func (m progressModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case progressMsg:
+		i, ok := m.index[msg.name]
+		if !ok {
+			i = len(m.rows)
+			m.index[msg.name] = i
+			m.rows = append(m.rows, progressRow{name: msg.name})
+		}
+		m.rows[i].phase = msg.phase
+		m.rows[i].detail = msg.detail
+	}
+	return m, nil
+}
+
+func (m progressModel) View() string {
+	var sb strings.Builder
+
+	nameWidth := 0
+	for _, row := range m.rows {
+		nameWidth = max(nameWidth, len(row.name))
+	}
+
+	sb.WriteString(tableHeaderStyle.Render(m.title))
+	sb.WriteString("\n")
+	for _, row := range m.rows {
+		sb.WriteString("  ")
+		sb.WriteString(progressNameStyle.Render(padRight(row.name, nameWidth)))
+		sb.WriteString("  ")
+		sb.WriteString(progressPhaseStyles[row.phase].Render(padRight(row.phase.String(), len("checking out"))))
+		if row.detail != "" {
+			sb.WriteString("  ")
+			sb.WriteString(progressDetailStyle.Render(row.detail))
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	_ "embed"
 	"encoding/json"
 	"fmt"
@@ -77,7 +78,7 @@ func UpdateConfig(projectDir string, updates []UpdatePath) error {
 	}
 
 	// Convert the updated config back to JSON
-	updatedConfigBytes, err := json.MarshalIndent(config, "", "  ")
+	updatedConfigBytes, err := marshalIndent(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal updated config: %v", err)
 	}
@@ -91,6 +92,19 @@ func UpdateConfig(projectDir string, updates []UpdatePath) error {
 	return nil
 }
 
+// marshalIndent is json.MarshalIndent without HTML escaping, so version ranges
+// such as ">=1.0 <2.0" stay readable in the files nep writes.
+func marshalIndent(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
 func nestedUpdate(config map[string]interface{}, keys []string, value interface{}) {
 	lastKey := keys[len(keys)-1]
 	m := config
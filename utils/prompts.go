@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -107,17 +108,28 @@ func (m groupedTextInputModel) View() string {
 	return sb.String()
 }
 
-// SelectFromList creates an interactive list for selection
+// SelectFromList creates an interactive list for selection. Leaving it with
+// q, esc or ctrl+c picks the highlighted item.
 func SelectFromList(title string, items []Item, itemsToShow int) (string, error) {
+	return selectFromList(title, items, itemsToShow, false)
+}
+
+// FilterFromList is SelectFromList for long lists: typing / filters the items,
+// and leaving the list any other way than with enter returns ErrPromptCancelled
+func FilterFromList(title string, items []Item, itemsToShow int) (string, error) {
+	return selectFromList(title, items, itemsToShow, true)
+}
+
+func selectFromList(title string, items []Item, itemsToShow int, filtering bool) (string, error) {
 	const listWidth = 60
 
 	l := list.New(toListItems(items), list.NewDefaultDelegate(), listWidth, (itemsToShow+1)*3)
 	l.Title = title
-	l.SetShowStatusBar(false)
-	l.SetFilteringEnabled(false)
-	l.SetShowHelp(false)
+	l.SetShowStatusBar(filtering)
+	l.SetFilteringEnabled(filtering)
+	l.SetShowHelp(filtering)
 
-	m := listModel{list: l}
+	m := listModel{list: l, filtering: filtering}
 	p := tea.NewProgram(m)
 	finalModel, err := p.Run()
 	if err != nil {
@@ -128,6 +140,10 @@ func SelectFromList(title string, items []Item, itemsToShow int) (string, error)
 	if !ok {
 		return "", fmt.Errorf("could not get user selection")
 	}
+	// A signal also ends the program, so only enter counts as a choice
+	if finalListModel.filtering && !finalListModel.chosen {
+		return "", ErrPromptCancelled
+	}
 
 	selectedItem, ok := finalListModel.list.SelectedItem().(Item)
 	if !ok {
@@ -147,7 +163,10 @@ func toListItems(items []Item) []list.Item {
 
 type listModel struct {
 	list list.Model
-	err  error
+	// filtering lists can be cancelled, plain ones always return the highlighted item
+	filtering bool
+	chosen    bool
+	err       error
 }
 
 func (m listModel) Init() tea.Cmd { return nil }
@@ -155,7 +174,18 @@ func (m listModel) Init() tea.Cmd { return nil }
 func (m listModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		if msg.String() == "q" || msg.String() == "esc" || msg.String() == "ctrl+c" || msg.String() == "enter" {
+		// While a filter is typed or shown, keys and esc belong to the list
+		filterState := m.list.FilterState()
+		switch {
+		case msg.String() == "ctrl+c":
+			return m, tea.Quit
+		case filterState == list.Filtering:
+		// A filter that matches nothing leaves nothing to pick
+		case msg.String() == "enter" && m.list.SelectedItem() != nil:
+			m.chosen = true
+			return m, tea.Quit
+		case msg.String() == "esc" && filterState == list.FilterApplied:
+		case msg.String() == "q" || msg.String() == "esc":
 			return m, tea.Quit
 		}
 	case error:
@@ -171,3 +201,64 @@ func (m listModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m listModel) View() string {
 	return "\n" + m.list.View()
 }
+
+// ErrPromptCancelled is returned when the user leaves a prompt that can be cancelled.
+var ErrPromptCancelled = errors.New("cancelled")
+
+// SecretInput prompts for a value, such as a token, without echoing it
+func SecretInput(prompt string) (string, error) {
+	ti := textinput.New()
+	ti.Placeholder = prompt
+	ti.EchoMode = textinput.EchoPassword
+	ti.EchoCharacter = '•'
+	ti.Focus()
+
+	p := tea.NewProgram(secretInputModel{input: ti})
+	finalModel, err := p.Run()
+	if err != nil {
+		return "", err
+	}
+
+	finalSecretModel, ok := finalModel.(secretInputModel)
+	if !ok {
+		return "", fmt.Errorf("could not get user input")
+	}
+	if finalSecretModel.cancelled {
+		return "", ErrPromptCancelled
+	}
+	return finalSecretModel.input.Value(), nil
+}
+
+type secretInputModel struct {
+	input     textinput.Model
+	done      bool
+	cancelled bool
+}
+
+func (m secretInputModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m secretInputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.Type {
+		case tea.KeyEnter:
+			m.done = true
+			return m, tea.Quit
+		case tea.KeyCtrlC, tea.KeyEsc:
+			m.done, m.cancelled = true, true
+			return m, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m secretInputModel) View() string {
+	if m.done {
+		return ""
+	}
+	return "\n(press enter to confirm, ctrl+c or esc to quit)\n" + focusedStyle.Render(m.input.View()) + "\n"
+}
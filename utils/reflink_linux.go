@@ -0,0 +1,26 @@
+//go:build linux
+
+package utils
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflinkFile creates dst as a copy-on-write clone of src (btrfs, xfs and similar).
+func reflinkFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return unix.IoctlFileClone(int(out.Fd()), int(in.Fd()))
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package utils
+
+import (
+	"errors"
+	"os"
+)
+
+// reflinkFile is only implemented on Linux; other platforms fall back to copying.
+func reflinkFile(src, dst string, perm os.FileMode) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,438 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version. Missing minor or patch numbers are treated as zero.
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          string
+	Original            string
+}
+
+// ParseVersion parses versions such as "1.2.3", "v1.2" or "2.0.0-beta.1".
+func ParseVersion(s string) (Version, error) {
+	original := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+
+	// Build metadata never affects precedence
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+
+	var prerelease string
+	if i := strings.Index(s, "-"); i >= 0 {
+		s, prerelease = s[:i], s[i+1:]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 || parts[0] == "" {
+		return Version{}, fmt.Errorf("invalid version: %q", original)
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version: %q", original)
+		}
+		numbers[i] = n
+	}
+
+	return Version{
+		Major:      numbers[0],
+		Minor:      numbers[1],
+		Patch:      numbers[2],
+		Prerelease: prerelease,
+		Original:   original,
+	}, nil
+}
+
+// Compare returns -1, 0 or 1 depending on whether v is lower than, equal to or higher than o.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+
+	// A prerelease has lower precedence than the associated normal version
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(o.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		ai, aErr := strconv.Atoi(a[i])
+		bi, bErr := strconv.Atoi(b[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			return sign(ai - bi)
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			return sign(strings.Compare(a[i], b[i]))
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// comparator is a single "<op> <version>" condition.
+type comparator struct {
+	op      string
+	version Version
+}
+
+func (c comparator) check(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "!=":
+		return cmp != 0
+	}
+	return cmp == 0
+}
+
+// Constraint is a version range such as "^1.2.0", "~1.4", ">=2 <3", "1.x || 2.x" or "*".
+// A constraint is a union of alternatives, each of which is a set of comparators that must all hold.
+type Constraint struct {
+	alternatives [][]comparator
+	raw          string
+}
+
+// ParseConstraint parses an npm/cargo style version range.
+// An empty string, "*" and "latest" match every release.
+func ParseConstraint(s string) (Constraint, error) {
+	raw := strings.TrimSpace(s)
+	constraint := Constraint{raw: raw}
+
+	for _, alternative := range strings.Split(raw, "||") {
+		comparators, err := parseAlternative(alternative)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: %v", raw, err)
+		}
+		constraint.alternatives = append(constraint.alternatives, comparators)
+	}
+
+	return constraint, nil
+}
+
+// IsExactVersion reports whether s names a single version rather than a range.
+func IsExactVersion(s string) bool {
+	v, err := ParseVersion(s)
+	if err != nil {
+		return false
+	}
+	// "1" and "1.2" are partial versions and therefore ranges
+	return strings.Count(strings.TrimPrefix(v.Original, "v"), ".") >= 2
+}
+
+func parseAlternative(s string) ([]comparator, error) {
+	// Hyphen ranges: "1.2.3 - 2.3.4"
+	if parts := strings.Split(s, " - "); len(parts) == 2 {
+		low, err := parsePartial(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		high, err := parsePartial(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		comparators := []comparator{{">=", low.floor()}}
+		if high.isWildcard() {
+			return comparators, nil
+		}
+		if high.parts < 3 {
+			return append(comparators, comparator{"<", high.ceiling()}), nil
+		}
+		return append(comparators, comparator{"<=", high.floor()}), nil
+	}
+
+	// Commas separate comparators in rockspec style ranges (">= 1.0, < 2.0")
+	fields := strings.Fields(strings.ReplaceAll(s, ",", " "))
+
+	// Allow a space between the operator and the version (">= 1.0")
+	var tokens []string
+	for i := 0; i < len(fields); i++ {
+		if isOperator(fields[i]) && i+1 < len(fields) {
+			tokens = append(tokens, fields[i]+fields[i+1])
+			i++
+			continue
+		}
+		tokens = append(tokens, fields[i])
+	}
+
+	var comparators []comparator
+	for _, token := range tokens {
+		parsed, err := parseComparator(token)
+		if err != nil {
+			return nil, err
+		}
+		comparators = append(comparators, parsed...)
+	}
+	return comparators, nil
+}
+
+func isOperator(s string) bool {
+	switch s {
+	case "^", "~", "~>", "=", "==", "<", "<=", ">", ">=", "!=", "~=":
+		return true
+	}
+	return false
+}
+
+func parseComparator(token string) ([]comparator, error) {
+	if token == "*" || strings.EqualFold(token, "latest") || strings.EqualFold(token, "x") {
+		return nil, nil
+	}
+
+	op := ""
+	for _, candidate := range []string{"~>", ">=", "<=", "==", "!=", "~=", "^", "~", ">", "<", "="} {
+		if strings.HasPrefix(token, candidate) {
+			op = candidate
+			break
+		}
+	}
+
+	p, err := parsePartial(strings.TrimPrefix(token, op))
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "", "=", "==":
+		if p.isWildcard() {
+			return nil, nil
+		}
+		if p.parts < 3 {
+			return []comparator{{">=", p.floor()}, {"<", p.ceiling()}}, nil
+		}
+		return []comparator{{"=", p.floor()}}, nil
+	case "!=", "~=":
+		return []comparator{{"!=", p.floor()}}, nil
+	case ">", "<=":
+		if p.parts < 3 {
+			// ">1.2" means ">=1.3.0" and "<=1.2" means "<1.3.0"
+			ceil := p.ceiling()
+			if op == ">" {
+				return []comparator{{">=", ceil}}, nil
+			}
+			return []comparator{{"<", ceil}}, nil
+		}
+		return []comparator{{op, p.floor()}}, nil
+	case ">=", "<":
+		return []comparator{{op, p.floor()}}, nil
+	case "~", "~>":
+		// ~1.2.3 := >=1.2.3 <1.3.0, ~1.2 := >=1.2.0 <1.3.0, ~1 := >=1.0.0 <2.0.0
+		// The luarocks "~>" operator has the same meaning
+		upper := p
+		if p.parts > 2 {
+			upper.parts = 2
+		}
+		return []comparator{{">=", p.floor()}, {"<", upper.ceiling()}}, nil
+	case "^":
+		// ^1.2.3 := >=1.2.3 <2.0.0, ^0.2.3 := >=0.2.3 <0.3.0, ^0.0.3 := >=0.0.3 <0.0.4
+		upper := p
+		switch {
+		case p.major > 0 || p.parts == 1:
+			upper.parts = 1
+		case p.minor > 0 || p.parts == 2:
+			upper.parts = 2
+		}
+		return []comparator{{">=", p.floor()}, {"<", upper.ceiling()}}, nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+// partial is a possibly incomplete version such as "1", "1.2" or "1.x".
+type partial struct {
+	major, minor, patch int
+	prerelease          string
+	parts               int
+}
+
+func parsePartial(s string) (partial, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if s == "" {
+		return partial{}, fmt.Errorf("missing version")
+	}
+
+	var prerelease string
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		s, prerelease = s[:i], s[i+1:]
+	}
+
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 {
+		return partial{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	p := partial{prerelease: prerelease}
+	numbers := []*int{&p.major, &p.minor, &p.patch}
+	for i, field := range fields {
+		if field == "x" || field == "X" || field == "*" {
+			break
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return partial{}, fmt.Errorf("invalid version %q", s)
+		}
+		*numbers[i] = n
+		p.parts++
+	}
+	return p, nil
+}
+
+func (p partial) isWildcard() bool {
+	return p.parts == 0
+}
+
+// floor is the lowest version the partial matches.
+func (p partial) floor() Version {
+	return Version{Major: p.major, Minor: p.minor, Patch: p.patch, Prerelease: p.prerelease}
+}
+
+// ceiling is the lowest version above everything the partial matches.
+func (p partial) ceiling() Version {
+	switch p.parts {
+	case 1:
+		return Version{Major: p.major + 1, Prerelease: "0"}
+	case 2:
+		return Version{Major: p.major, Minor: p.minor + 1, Prerelease: "0"}
+	}
+	return Version{Major: p.major, Minor: p.minor, Patch: p.patch + 1, Prerelease: "0"}
+}
+
+// Check reports whether v satisfies the constraint.
+// Prereleases only match when the constraint mentions a prerelease of the same version.
+func (c Constraint) Check(v Version) bool {
+	for _, comparators := range c.alternatives {
+		if matchesAll(comparators, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAll(comparators []comparator, v Version) bool {
+	allowPrerelease := v.Prerelease == ""
+	for _, c := range comparators {
+		if !c.check(v) {
+			return false
+		}
+		if c.version.Prerelease != "" && c.version.Prerelease != "0" &&
+			c.version.Major == v.Major && c.version.Minor == v.Minor && c.version.Patch == v.Patch {
+			allowPrerelease = true
+		}
+	}
+	return allowPrerelease
+}
+
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Satisfies reports whether version matches constraint; an empty constraint matches anything.
+func Satisfies(version, constraint string) bool {
+	if constraint == "" {
+		return true
+	}
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	v, err := ParseVersion(version)
+	if err != nil {
+		return false
+	}
+	return c.Check(v)
+}
+
+// DescribeConstraint returns the constraint for messages, naming the empty one.
+func DescribeConstraint(constraint string) string {
+	if constraint == "" {
+		return "any version"
+	}
+	return constraint
+}
+
+// MaxSatisfying returns the highest version in versions that satisfies the constraint.
+// Entries that are not valid versions are ignored.
+func (c Constraint) MaxSatisfying(versions []string) (string, bool) {
+	sorted := SortVersions(versions)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		v, _ := ParseVersion(sorted[i])
+		if c.Check(v) {
+			return sorted[i], true
+		}
+	}
+	return "", false
+}
+
+// SortVersions returns the valid versions in ascending order.
+func SortVersions(versions []string) []string {
+	type parsed struct {
+		raw     string
+		version Version
+	}
+
+	var valid []parsed
+	for _, raw := range versions {
+		v, err := ParseVersion(raw)
+		if err == nil {
+			valid = append(valid, parsed{raw, v})
+		}
+	}
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].version.Compare(valid[j].version) < 0
+	})
+
+	sorted := make([]string, len(valid))
+	for i, p := range valid {
+		sorted[i] = p.raw
+	}
+	return sorted
+}
@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1.2.3", "1.2.3"},
+		{"v1.2.3", "1.2.3"},
+		{"1.2", "1.2.0"},
+		{"1", "1.0.0"},
+		{"2.0.0-beta.1", "2.0.0-beta.1"},
+		{"1.2.3+build.5", "1.2.3"},
+		{" 1.2.3 ", "1.2.3"},
+	}
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.in)
+		if err != nil {
+			t.Errorf("ParseVersion(%q): %v", tt.in, err)
+			continue
+		}
+		if got := v.String(); got != tt.want {
+			t.Errorf("ParseVersion(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "v", "1.2.3.4", "a.b.c", "1.-2.3", "1..2"} {
+		if _, err := ParseVersion(in); err == nil {
+			t.Errorf("ParseVersion(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	// Each version is lower than the next, following the semver precedence rules
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := ParseVersion(ordered[i])
+			b, _ := ParseVersion(ordered[j])
+			want := sign(i - j)
+			if got := a.Compare(b); got != want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	a, _ := ParseVersion("v1.2")
+	b, _ := ParseVersion("1.2.0+meta")
+	if a.Compare(b) != 0 {
+		t.Errorf("v1.2 and 1.2.0+meta should be equal")
+	}
+}
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		reject     []string
+	}{
+		// Caret
+		{"^1.2.3", []string{"1.2.3", "1.9.9"}, []string{"1.2.2", "2.0.0", "2.0.0-alpha"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "0.2.2"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0", "0.9.0"}},
+		{"^0", []string{"0.0.1", "0.9.0"}, []string{"1.0.0"}},
+		// Tilde
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		// Hyphen ranges
+		{"1.2.3 - 2.3.4", []string{"1.2.3", "2.3.4"}, []string{"1.2.2", "2.3.5"}},
+		{"1.2 - 2.3", []string{"1.2.0", "2.3.9"}, []string{"1.1.9", "2.4.0"}},
+		{"1.2.3 - *", []string{"1.2.3", "9.0.0"}, []string{"1.2.2"}},
+		// X-ranges, partial and exact versions
+		{"1.x", []string{"1.0.0", "1.9.9"}, []string{"2.0.0", "0.9.9"}},
+		{"1.2.*", []string{"1.2.0", "1.2.9"}, []string{"1.3.0"}},
+		{"1.2", []string{"1.2.0", "1.2.5"}, []string{"1.3.0"}},
+		{"1.2.3", []string{"1.2.3", "v1.2.3"}, []string{"1.2.4"}},
+		{"=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		// Comparators
+		{">=1.2.0 <2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{">1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"!=1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		// Alternatives
+		{"1.x || >=3.1", []string{"1.5.0", "3.1.0", "4.0.0"}, []string{"2.0.0", "3.0.0"}},
+		// Anything
+		{"", []string{"0.0.1", "9.9.9"}, []string{"1.0.0-beta"}},
+		{"*", []string{"0.0.1", "9.9.9"}, nil},
+		{"latest", []string{"1.0.0"}, nil},
+		// Prereleases only match ranges naming a prerelease of the same version
+		{"^1.2.3-beta.1", []string{"1.2.3-beta.1", "1.2.3-beta.2", "1.2.3", "1.5.0"}, []string{"1.2.3-alpha", "1.5.0-beta"}},
+		{">=1.0.0-rc.1", []string{"1.0.0-rc.2", "1.0.0", "2.0.0"}, []string{"2.0.0-rc.1"}},
+		{"^1.0.0", []string{"1.0.0"}, []string{"1.1.0-beta"}},
+		// Rockspec style ranges
+		{"~> 1.2", []string{"1.2.0", "1.2.7"}, []string{"1.3.0", "1.1.9"}},
+		{"~> 2", []string{"2.0.0", "2.9.0"}, []string{"3.0.0"}},
+		{">= 1.0, < 2.0", []string{"1.0.0", "1.9.9"}, []string{"0.9.9", "2.0.0"}},
+		{"== 1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"~= 1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q): %v", tt.constraint, err)
+			continue
+		}
+		for _, raw := range tt.match {
+			v, _ := ParseVersion(raw)
+			if !c.Check(v) {
+				t.Errorf("%q should match %s", tt.constraint, raw)
+			}
+		}
+		for _, raw := range tt.reject {
+			v, _ := ParseVersion(raw)
+			if c.Check(v) {
+				t.Errorf("%q should not match %s", tt.constraint, raw)
+			}
+		}
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, in := range []string{">>1", "^a.b", "1.2.3.4", "1.2 - "} {
+		if _, err := ParseConstraint(in); err == nil {
+			t.Errorf("ParseConstraint(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestIsExactVersion(t *testing.T) {
+	tests := map[string]bool{
+		"1.2.3":        true,
+		"v1.2.3":       true,
+		"1.2.3-beta.1": true,
+		"1.2":          false,
+		"1":            false,
+		"^1.2.3":       false,
+		"1.x":          false,
+	}
+	for in, want := range tests {
+		if got := IsExactVersion(in); got != want {
+			t.Errorf("IsExactVersion(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestMaxSatisfying(t *testing.T) {
+	versions := []string{"v1.0.0", "1.2.0", "2.0.0-beta", "1.10.0", "not-a-version", "2.1.0"}
+	tests := []struct {
+		constraint string
+		want       string
+		ok         bool
+	}{
+		{"^1", "1.10.0", true},
+		{"~1.2", "1.2.0", true},
+		{"<1.1", "v1.0.0", true},
+		{"*", "2.1.0", true},
+		{"^3", "", false},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := c.MaxSatisfying(versions)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("MaxSatisfying(%q) = %q, %v, want %q, %v", tt.constraint, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSortVersions(t *testing.T) {
+	got := SortVersions([]string{"1.10.0", "v1.2.0", "bad", "1.2.0-rc.1", "0.9.0"})
+	want := []string{"0.9.0", "1.2.0-rc.1", "v1.2.0", "1.10.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortVersions = %v, want %v", got, want)
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"1.2.3", "", true},
+		{"1.2.3", "^1.2", true},
+		{"2.0.0", "^1.2", false},
+		{"v1.0.0", ">= 1.0, < 2.0", true},
+		{"not-a-version", "*", false},
+		{"1.2.3", ">>1", false},
+	}
+	for _, tt := range tests {
+		if got := Satisfies(tt.version, tt.constraint); got != tt.want {
+			t.Errorf("Satisfies(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeConstraint(t *testing.T) {
+	if got := DescribeConstraint(""); got != "any version" {
+		t.Errorf("DescribeConstraint(\"\") = %q, want \"any version\"", got)
+	}
+	if got := DescribeConstraint("^1.2"); got != "^1.2" {
+		t.Errorf("DescribeConstraint(\"^1.2\") = %q, want \"^1.2\"", got)
+	}
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// SourceSpecifier is a dependency installed straight from a git repository or a
+// local directory instead of through the registry:
+//
+//	github:kikito/inspect.lua#v3.1.3
+//	https://git.example.com/x.git#main
+//	git@git.example.com:team/x.git
+//	file:../shared-lib
+//
+// file:// URLs are repositories, while file: paths are plain directories.
+// The part after # is a tag, branch or commit; without it the default branch is used.
+type SourceSpecifier struct {
+	// URL is the repository to clone; empty for local directories
+	URL string
+	Ref string
+	// Path is the directory of a file: specifier, relative to the package declaring it
+	Path string
+}
+
+// ParseSourceSpecifier parses s, reporting false when it is a plain version constraint.
+func ParseSourceSpecifier(s string) (SourceSpecifier, bool) {
+	if dir, ok := strings.CutPrefix(s, "file:"); ok && !strings.HasPrefix(dir, "//") {
+		return SourceSpecifier{Path: filepath.FromSlash(dir)}, dir != ""
+	}
+
+	url, ref, _ := strings.Cut(s, "#")
+	switch {
+	case strings.HasPrefix(url, "github:"):
+		repo := strings.TrimSuffix(strings.TrimPrefix(url, "github:"), ".git")
+		if strings.Count(repo, "/") != 1 {
+			return SourceSpecifier{}, false
+		}
+		url = "https://github.com/" + repo + ".git"
+	case strings.HasPrefix(url, "git+"):
+		url = strings.TrimPrefix(url, "git+")
+	case strings.HasPrefix(url, "https://"), strings.HasPrefix(url, "http://"),
+		strings.HasPrefix(url, "ssh://"), strings.HasPrefix(url, "git://"), strings.HasPrefix(url, "file://"),
+		strings.HasPrefix(url, "git@"):
+	default:
+		return SourceSpecifier{}, false
+	}
+
+	return SourceSpecifier{URL: url, Ref: ref}, true
+}
+
+// IsSourceSpecifier reports whether s names a repository or directory rather than a version.
+func IsSourceSpecifier(s string) bool {
+	_, ok := ParseSourceSpecifier(s)
+	return ok
+}
+
+// IsLocal reports whether the specifier points at a local directory.
+func (s SourceSpecifier) IsLocal() bool {
+	return s.URL == ""
+}
+
+// Name derives a package name from the last element of the repository URL or path.
+func (s SourceSpecifier) Name() string {
+	if s.IsLocal() {
+		return filepath.Base(filepath.Clean(s.Path))
+	}
+	url := strings.TrimSuffix(strings.TrimRight(s.URL, "/"), ".git")
+	return url[strings.LastIndexAny(url, "/:")+1:]
+}
+
+// Refs returns the refs to check out, or nil for the repository's default branch.
+func (s SourceSpecifier) Refs() []string {
+	if s.Ref == "" {
+		return nil
+	}
+	return []string{s.Ref}
+}
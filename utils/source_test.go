@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSourceSpecifier(t *testing.T) {
+	tests := []struct {
+		in   string
+		want SourceSpecifier
+		ok   bool
+	}{
+		{"github:kikito/inspect.lua#v3.1.3", SourceSpecifier{URL: "https://github.com/kikito/inspect.lua.git", Ref: "v3.1.3"}, true},
+		{"github:kikito/inspect.lua.git", SourceSpecifier{URL: "https://github.com/kikito/inspect.lua.git"}, true},
+		{"https://git.example.com/x.git#main", SourceSpecifier{URL: "https://git.example.com/x.git", Ref: "main"}, true},
+		{"git+ssh://git@git.example.com/team/x.git", SourceSpecifier{URL: "ssh://git@git.example.com/team/x.git"}, true},
+		{"git@git.example.com:team/x.git#4f2c1e9", SourceSpecifier{URL: "git@git.example.com:team/x.git", Ref: "4f2c1e9"}, true},
+		{"file:///srv/git/x.git", SourceSpecifier{URL: "file:///srv/git/x.git"}, true},
+		{"file:../shared-lib", SourceSpecifier{Path: filepath.FromSlash("../shared-lib")}, true},
+		// Not source specifiers
+		{"github:kikito", SourceSpecifier{}, false},
+		{"file:", SourceSpecifier{}, false},
+		{"^1.2.0", SourceSpecifier{}, false},
+		{"1.0.0", SourceSpecifier{}, false},
+		{"", SourceSpecifier{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseSourceSpecifier(tt.in)
+		if ok != tt.ok || (ok && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("ParseSourceSpecifier(%q) = %+v, %v, want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+		if IsSourceSpecifier(tt.in) != tt.ok {
+			t.Errorf("IsSourceSpecifier(%q) = %v, want %v", tt.in, !tt.ok, tt.ok)
+		}
+	}
+}
+
+func TestSourceSpecifierName(t *testing.T) {
+	tests := map[string]string{
+		"github:kikito/inspect.lua":             "inspect.lua",
+		"https://git.example.com/team/x.git#v1": "x",
+		"https://git.example.com/team/y/":       "y",
+		"git@git.example.com:z.git":             "z",
+		"file:../libs/shared-lib/":              "shared-lib",
+	}
+	for in, want := range tests {
+		spec, ok := ParseSourceSpecifier(in)
+		if !ok {
+			t.Errorf("ParseSourceSpecifier(%q) failed", in)
+			continue
+		}
+		if got := spec.Name(); got != want {
+			t.Errorf("Name of %q = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSourceSpecifierRefs(t *testing.T) {
+	spec, _ := ParseSourceSpecifier("github:a/b")
+	if refs := spec.Refs(); refs != nil {
+		t.Errorf("Refs without a ref = %v, want nil for the default branch", refs)
+	}
+	spec, _ = ParseSourceSpecifier("github:a/b#v1.0.0")
+	if refs := spec.Refs(); !reflect.DeepEqual(refs, []string{"v1.0.0"}) {
+		t.Errorf("Refs = %v, want [v1.0.0]", refs)
+	}
+}
@@ -0,0 +1,334 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// The package store is shared by every project of the current user:
+//
+//	<store>/<url hash>/repo       bare mirror of the repository, used to resolve refs
+//	<store>/<url hash>/<commit>   checked out tree of a single commit, without .git
+//
+// Trees are immutable once written and are linked or copied into nebpack/.
+
+// StoreDir returns the package store directory, ~/.cache/nep/store unless NEP_STORE is set.
+func StoreDir() (string, error) {
+	if dir := os.Getenv("NEP_STORE"); dir != "" {
+		return dir, nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache directory: %v", err)
+	}
+	return filepath.Join(cacheDir, "nep", "store"), nil
+}
+
+// storeRepoDir returns the store directory for a repository URL.
+func storeRepoDir(url string) (string, error) {
+	storeDir, err := StoreDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(storeDir, hex.EncodeToString(sum[:])[:32]), nil
+}
+
+// StoreTreeDir returns where the tree of url at commit lives in the store, and whether it exists.
+func StoreTreeDir(url, commit string) (string, bool) {
+	repoDir, err := storeRepoDir(url)
+	if err != nil || commit == "" {
+		return "", false
+	}
+	treeDir := filepath.Join(repoDir, commit)
+	_, err = os.Stat(treeDir)
+	return treeDir, err == nil
+}
+
+// StoreFetch makes sure the first of refs that exists in the repository at url is
+// checked out in the store, and returns its commit SHA and tree directory.
+// The network is only used when the store cannot already answer the request.
+func StoreFetch(url string, refs []string, progress io.Writer) (string, string, error) {
+	if len(refs) > 0 {
+		if commit, treeDir, err := StoreLookup(url, refs); err == nil {
+			return commit, treeDir, nil
+		}
+	}
+	return StoreSync(url, refs, progress)
+}
+
+// StoreSync is StoreFetch that always fetches the repository first, for refs
+// such as branches whose commit may have moved since the last fetch.
+func StoreSync(url string, refs []string, progress io.Writer) (string, string, error) {
+	repoDir, err := storeRepoDir(url)
+	if err != nil {
+		return "", "", err
+	}
+
+	repo, err := openStoreRepo(filepath.Join(repoDir, "repo"), url, progress)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := fetchStoreRepo(repo, url, progress); err != nil {
+		return "", "", err
+	}
+
+	var hash plumbing.Hash
+	if len(refs) > 0 {
+		if hash, err = resolveRefs(repo, refs); err != nil {
+			return "", "", fmt.Errorf("%v in %s", err, url)
+		}
+	} else {
+		head, err := repo.Head()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read HEAD of %s: %v", url, err)
+		}
+		hash = head.Hash()
+	}
+
+	return checkoutStoreTree(repo, repoDir, hash, progress)
+}
+
+// StoreLookup is StoreFetch without any network access: it only succeeds when
+// the tree is already in the store or the ref resolves in the local mirror.
+func StoreLookup(url string, refs []string) (string, string, error) {
+	// A pinned commit that is already in the store needs no repository access at all
+	if len(refs) == 1 && plumbing.IsHash(refs[0]) {
+		if treeDir, ok := StoreTreeDir(url, refs[0]); ok {
+			return refs[0], treeDir, nil
+		}
+	}
+
+	repoDir, err := storeRepoDir(url)
+	if err != nil {
+		return "", "", err
+	}
+
+	repo, err := git.PlainOpen(filepath.Join(repoDir, "repo"))
+	if err != nil {
+		return "", "", fmt.Errorf("%s is not in the package store", url)
+	}
+
+	hash, err := resolveRefs(repo, refs)
+	if err != nil {
+		return "", "", fmt.Errorf("%v in the package store copy of %s", err, url)
+	}
+
+	return checkoutStoreTree(repo, repoDir, hash, nil)
+}
+
+// checkoutStoreTree returns the tree directory for hash, writing it first if needed.
+func checkoutStoreTree(repo *git.Repository, repoDir string, hash plumbing.Hash, progress io.Writer) (string, string, error) {
+	commit := hash.String()
+	treeDir := filepath.Join(repoDir, commit)
+	if _, err := os.Stat(treeDir); err == nil {
+		return commit, treeDir, nil
+	}
+
+	setPhase(progress, PhaseCheckingOut)
+	if err := writeStoreTree(repo, hash, treeDir); err != nil {
+		return "", "", err
+	}
+	return commit, treeDir, nil
+}
+
+// openStoreRepo opens the bare mirror for url, cloning it on first use.
+func openStoreRepo(repoDir, url string, progress io.Writer) (*git.Repository, error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err == nil {
+		return repo, nil
+	}
+	if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("failed to open store repository for %s: %v", url, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(repoDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %v", err)
+	}
+
+	// Clone next to the final location so a failed clone never leaves a broken mirror behind
+	tempDir, err := os.MkdirTemp(filepath.Dir(repoDir), "repo-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	auth, err := GitAuth(url)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := git.PlainClone(tempDir, true, &git.CloneOptions{
+		URL:      url,
+		Auth:     auth,
+		Mirror:   true,
+		Progress: progress,
+	}); err != nil {
+		return nil, GitAuthError(url, err)
+	}
+
+	if err := os.Rename(tempDir, repoDir); err != nil {
+		// Another nep process may have populated the store first
+		if _, statErr := os.Stat(repoDir); statErr != nil {
+			return nil, fmt.Errorf("failed to move repository into store: %v", err)
+		}
+	}
+
+	return git.PlainOpen(repoDir)
+}
+
+func fetchStoreRepo(repo *git.Repository, url string, progress io.Writer) error {
+	auth, err := GitAuth(url)
+	if err != nil {
+		return err
+	}
+	err = repo.Fetch(&git.FetchOptions{Auth: auth, Progress: progress, Force: true})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch: %v", GitAuthError(url, err))
+	}
+	return nil
+}
+
+// writeStoreTree writes the files of a commit to treeDir.
+func writeStoreTree(repo *git.Repository, hash plumbing.Hash, treeDir string) error {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return fmt.Errorf("failed to read commit %s: %v", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to read tree of %s: %v", hash, err)
+	}
+
+	tempDir, err := os.MkdirTemp(filepath.Dir(treeDir), "tree-")
+	if err != nil {
+		return fmt.Errorf("failed to create store directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	err = tree.Files().ForEach(func(f *object.File) error {
+		return writeStoreFile(f, filepath.Join(tempDir, filepath.FromSlash(f.Name)))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check out %s: %v", hash, err)
+	}
+
+	if err := os.Rename(tempDir, treeDir); err != nil {
+		if _, statErr := os.Stat(treeDir); statErr != nil {
+			return fmt.Errorf("failed to move tree into store: %v", err)
+		}
+	}
+	return nil
+}
+
+func writeStoreFile(f *object.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	if f.Mode == filemode.Symlink {
+		link, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+	}
+
+	perm := os.FileMode(0644)
+	if f.Mode == filemode.Executable {
+		perm = 0755
+	}
+
+	reader, err := f.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// LinkTree populates dst with the contents of a store tree. Files are
+// reflinked where the filesystem supports it and copied otherwise.
+// NEP_LINK=hardlink shares files with the store instead, which saves the most
+// space but means editing a file in nebpack/ also edits it in the store.
+func LinkTree(src, dst string) error {
+	mode := os.Getenv("NEP_LINK")
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir() && info.Name() == ".git":
+			// Store trees have none; local directories must not bring theirs along
+			return filepath.SkipDir
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+
+		switch mode {
+		case "hardlink":
+			if err := os.Link(path, target); err == nil {
+				return nil
+			}
+		case "copy":
+		default:
+			if err := reflinkFile(path, target, info.Mode().Perm()); err == nil {
+				return nil
+			}
+			os.Remove(target)
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
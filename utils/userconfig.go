@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"nep/configs"
+)
+
+// UserConfigDir returns the per-user nep config directory, ~/.config/nep unless NEP_CONFIG_DIR is set.
+func UserConfigDir() (string, error) {
+	if dir := os.Getenv("NEP_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config directory: %v", err)
+	}
+	return filepath.Join(configDir, "nep"), nil
+}
+
+// ReadUserConfig decodes the user config file into v. A missing file leaves v untouched.
+func ReadUserConfig(v interface{}) error {
+	configDir, err := UserConfigDir()
+	if err != nil {
+		return err
+	}
+
+	configFilePath := filepath.Join(configDir, configs.UserConfigName+".json")
+	configFileBytes, err := os.ReadFile(configFilePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read user config: %v", err)
+	}
+
+	if err := json.Unmarshal(configFileBytes, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", configFilePath, err)
+	}
+	return nil
+}